@@ -28,10 +28,30 @@ type Service struct {
 }
 
 type App struct {
-	Port            int    `mapstructure:"port"`
-	Debug           bool   `mapstructure:"debug"`
-	Secret          string `mapstructure:"secret"`
-	MaxRestrictYear int    `mapstructure:"max_restrict_year"`
+	Port              int      `mapstructure:"port"`
+	HttpPort          int      `mapstructure:"http_port"`
+	Debug             bool     `mapstructure:"debug"`
+	Secret            string   `mapstructure:"secret"`
+	MaxSessions       int      `mapstructure:"max_sessions"`
+	AcademicYear      int      `mapstructure:"academic_year"`
+	AcademicYearStart string   `mapstructure:"academic_year_start"`
+	LoginStateTTL     int      `mapstructure:"login_state_ttl"`
+	TrustedProxies    []string `mapstructure:"trusted_proxies"`
+	PermissionRefresh int      `mapstructure:"permission_refresh"`
+}
+
+type EligibilityWindow struct {
+	StartAt string `mapstructure:"start_at"`
+	EndAt   string `mapstructure:"end_at"`
+}
+
+type Eligibility struct {
+	Years             []int               `mapstructure:"years"`
+	AllowedFaculties  []string            `mapstructure:"allowed_faculties"`
+	DeniedFaculties   []string            `mapstructure:"denied_faculties"`
+	AllowedStudentIDs []string            `mapstructure:"allowed_student_ids"`
+	DeniedStudentIDs  []string            `mapstructure:"denied_student_ids"`
+	Windows           []EligibilityWindow `mapstructure:"windows"`
 }
 
 type ChulaSSO struct {
@@ -40,26 +60,92 @@ type ChulaSSO struct {
 	DeeAppSecret string `mapstructure:"app-secret"`
 }
 
+type JwtKey struct {
+	KeyID      string `mapstructure:"key_id"`
+	Algorithm  string `mapstructure:"algorithm"`
+	Secret     string `mapstructure:"secret"`
+	PrivateKey string `mapstructure:"private_key"`
+	PublicKey  string `mapstructure:"public_key"`
+	RetireAt   string `mapstructure:"retire_at"`
+}
+
 type Jwt struct {
-	Secret    string `mapstructure:"secret"`
-	ExpiresIn int32  `mapstructure:"expires_in"`
-	Issuer    string `mapstructure:"issuer"`
+	Algorithm              string   `mapstructure:"algorithm"`
+	KeyID                  string   `mapstructure:"key_id"`
+	PrivateKey             string   `mapstructure:"private_key"`
+	Secret                 string   `mapstructure:"secret"`
+	VerificationKeys       []JwtKey `mapstructure:"verification_keys"`
+	IdentityClaims         bool     `mapstructure:"identity_claims"`
+	ExpiresIn              int32    `mapstructure:"expires_in"`
+	RefreshExpiresIn       int32    `mapstructure:"refresh_expires_in"`
+	ServiceExpiresIn       int32    `mapstructure:"service_expires_in"`
+	ImpersonationExpiresIn int32    `mapstructure:"impersonation_expires_in"`
+	MaxSessionAge          int32    `mapstructure:"max_session_age"`
+	Issuer                 string   `mapstructure:"issuer"`
 }
 
 type Oauth struct {
 	ClientID     string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
 	RedirectUri  string `mapstructure:"redirect_uri"`
+	HostedDomain string `mapstructure:"hosted_domain"`
+}
+
+type OidcProvider struct {
+	Name             string   `mapstructure:"name"`
+	IssuerUrl        string   `mapstructure:"issuer_url"`
+	ClientID         string   `mapstructure:"client_id"`
+	ClientSecret     string   `mapstructure:"client_secret"`
+	RedirectUri      string   `mapstructure:"redirect_uri"`
+	Scopes           []string `mapstructure:"scopes"`
+	TrustStudentID   bool     `mapstructure:"trust_student_id"`
+	StudentIDClaim   string   `mapstructure:"student_id_claim"`
+	StudentIDPattern string   `mapstructure:"student_id_pattern"`
+	FirstnameClaim   string   `mapstructure:"firstname_claim"`
+	LastnameClaim    string   `mapstructure:"lastname_claim"`
+	EmailClaim       string   `mapstructure:"email_claim"`
+}
+
+type MagicLink struct {
+	Url      string `mapstructure:"url"`
+	TTL      int    `mapstructure:"ttl"`
+	Cooldown int    `mapstructure:"cooldown"`
+}
+
+type Mail struct {
+	Driver   string `mapstructure:"driver"`
+	From     string `mapstructure:"from"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Path     string `mapstructure:"path"`
+}
+
+type Audit struct {
+	Publisher     string `mapstructure:"publisher"`
+	Stream        string `mapstructure:"stream"`
+	StreamMaxLen  int64  `mapstructure:"stream_max_len"`
+	WebhookUrl    string `mapstructure:"webhook_url"`
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	Path          string `mapstructure:"path"`
+	QueueSize     int    `mapstructure:"queue_size"`
+	MaxRetries    int    `mapstructure:"max_retries"`
 }
 
 type Config struct {
-	Redis    Redis    `mapstructure:"redis"`
-	Oauth    Oauth    `mapstructure:"google-oauth"`
-	Database Database `mapstructure:"database"`
-	App      App      `mapstructure:"app"`
-	ChulaSSO ChulaSSO `mapstructure:"chula-sso"`
-	Jwt      Jwt      `mapstructure:"jwt"`
-	Service  Service  `mapstructure:"service"`
+	Redis       Redis          `mapstructure:"redis"`
+	Oauth       Oauth          `mapstructure:"google-oauth"`
+	Database    Database       `mapstructure:"database"`
+	App         App            `mapstructure:"app"`
+	ChulaSSO    ChulaSSO       `mapstructure:"chula-sso"`
+	Jwt         Jwt            `mapstructure:"jwt"`
+	Service     Service        `mapstructure:"service"`
+	Eligibility Eligibility    `mapstructure:"eligibility"`
+	Oidc        []OidcProvider `mapstructure:"oidc"`
+	MagicLink   MagicLink      `mapstructure:"magic-link"`
+	Mail        Mail           `mapstructure:"mail"`
+	Audit       Audit          `mapstructure:"audit"`
 }
 
 func LoadConfig() (config *Config, err error) {
@@ -79,6 +165,17 @@ func LoadConfig() (config *Config, err error) {
 		return nil, errors.Wrap(err, "error occurs while unmarshal the config")
 	}
 
+	// max_restrict_year predates the eligibility rules, it still allows the years 1 to its value so an old config keeps its policy
+	if viper.IsSet("app.max_restrict_year") {
+		if viper.IsSet("eligibility.years") {
+			return nil, errors.New("app.max_restrict_year is replaced by eligibility.years, remove it from the config")
+		}
+
+		for year := 1; year <= viper.GetInt("app.max_restrict_year"); year++ {
+			config.Eligibility.Years = append(config.Eligibility.Years, year)
+		}
+	}
+
 	return
 }
 
@@ -88,6 +185,6 @@ func LoadOauthConfig(oauth Oauth) *oauth2.Config {
 		ClientSecret: oauth.ClientSecret,
 		RedirectURL:  oauth.RedirectUri,
 		Endpoint:     google.Endpoint,
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+		Scopes:       []string{"openid", "email", "profile"},
 	}
 }
@@ -1,64 +1,167 @@
 package client
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"io/ioutil"
 	"net/http"
-	"net/url"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/oauth2"
 )
 
+const (
+	googleJwksUrl             = "https://www.googleapis.com/oauth2/v3/certs"
+	defaultGoogleHostedDomain = "student.chula.ac.th"
+)
+
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
 type GoogleOauthClient struct {
-	oauthConfig *oauth2.Config
+	oauthConfig  *oauth2.Config
+	hostedDomain string
+	httpClient   *http.Client
+	jwks         *JwksCache
+	issuers      []string
 }
 
-func NewGoogleOauthClient(oauthConfig *oauth2.Config) *GoogleOauthClient {
+func NewGoogleOauthClient(oauthConfig *oauth2.Config, hostedDomain string) *GoogleOauthClient {
+	if hostedDomain == "" {
+		hostedDomain = defaultGoogleHostedDomain
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
 	return &GoogleOauthClient{
-		oauthConfig,
+		oauthConfig:  oauthConfig,
+		hostedDomain: hostedDomain,
+		httpClient:   httpClient,
+		jwks:         NewJwksCache(googleJwksUrl, httpClient),
+		issuers:      googleIssuers,
 	}
 }
 
 type GoogleUserEmailResponse struct {
-	Email     string `json:"email"`
-	Firstname string `json:"given_name"`
-	Lastname  string `json:"family_name"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Firstname     string `json:"given_name"`
+	Lastname      string `json:"family_name"`
+}
+
+type googleIdTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	HostedDomain  string `json:"hd"`
+	Nonce         string `json:"nonce"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
 }
 
 var (
-	InvalidCode   = errors.New("Invalid code")
-	HttpError     = errors.New("Unable to get user info")
-	IOError       = errors.New("Unable to read google response")
-	InvalidFormat = errors.New("Google sent unexpected format")
+	InvalidCode     = errors.New("Invalid code")
+	InvalidIdToken  = errors.New("Invalid id token")
+	ForbiddenDomain = errors.New("Email is not verified in the hosted domain")
+	HttpError       = errors.New("Unable to get user info")
+	InvalidFormat   = errors.New("Google sent unexpected format")
 )
 
-func (c *GoogleOauthClient) GetUserEmail(code string) (*GoogleUserEmailResponse, error) {
-	token, err := c.oauthConfig.Exchange(oauth2.NoContext, code)
+func (c *GoogleOauthClient) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	return c.oauthConfig.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("hd", c.hostedDomain),
+	)
+}
+
+// GetUserEmail exchanges the code with the PKCE verifier and returns the identity from the verified claims of the id token
+func (c *GoogleOauthClient) GetUserEmail(code string, codeVerifier string, nonce string) (*GoogleUserEmailResponse, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, c.httpClient)
+
+	token, err := c.oauthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
 	if err != nil {
 		log.Error().Err(err).Msg("Unable to exchange oauth token")
 		return nil, InvalidCode
 	}
 
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + url.QueryEscape(token.AccessToken))
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		log.Error().Msg("Google did not return an id token")
+		return nil, InvalidIdToken
+	}
+
+	claims, err := c.verifyIdToken(idToken, nonce)
 	if err != nil {
-		log.Error().Err(err).Msg("Unable to get user info")
-		return nil, HttpError
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	response, err := ioutil.ReadAll(resp.Body)
+	return &GoogleUserEmailResponse{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Firstname:     claims.GivenName,
+		Lastname:      claims.FamilyName,
+	}, nil
+}
+
+func (c *GoogleOauthClient) verifyIdToken(idToken string, nonce string) (*googleIdTokenClaims, error) {
+	claims := &googleIdTokenClaims{}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.jwks.Key(kid)
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("Unable to read user info response")
-		return nil, IOError
+		log.Error().Err(err).Msg("Unable to verify the google id token")
+		return nil, InvalidIdToken
+	}
+
+	if !claims.VerifyAudience(c.oauthConfig.ClientID, true) {
+		log.Error().Strs("aud", claims.Audience).Msg("Google id token is issued for another client")
+		return nil, InvalidIdToken
+	}
+
+	if !c.isValidIssuer(claims.Issuer) {
+		log.Error().Str("iss", claims.Issuer).Msg("Google id token has an unexpected issuer")
+		return nil, InvalidIdToken
+	}
+
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		log.Error().Msg("Google id token has an unexpected nonce")
+		return nil, InvalidIdToken
 	}
 
-	var parsedResponse GoogleUserEmailResponse
-	if err = json.Unmarshal(response, &parsedResponse); err != nil {
-		log.Error().Err(err).Msg("Google send unexpected response")
-		return nil, InvalidFormat
+	if claims.ExpiresAt == nil {
+		log.Error().Msg("Google id token has no expiry")
+		return nil, InvalidIdToken
+	}
+
+	if claims.Subject == "" {
+		log.Error().Msg("Google id token has no subject")
+		return nil, InvalidIdToken
+	}
+
+	if !claims.EmailVerified || claims.HostedDomain != c.hostedDomain {
+		log.Error().
+			Str("email", claims.Email).
+			Str("hd", claims.HostedDomain).
+			Msg("Google account is not a verified account of the hosted domain")
+		return nil, ForbiddenDomain
+	}
+
+	return claims, nil
+}
+
+func (c *GoogleOauthClient) isValidIssuer(iss string) bool {
+	for _, issuer := range c.issuers {
+		if iss == issuer {
+			return true
+		}
 	}
 
-	return &parsedResponse, nil
+	return false
 }
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/oauth2"
+)
+
+type GoogleOauthClientTest struct {
+	suite.Suite
+	key     *rsa.PrivateKey
+	server  *httptest.Server
+	idToken string
+	claims  *googleIdTokenClaims
+	client  *GoogleOauthClient
+}
+
+func TestGoogleOauthClient(t *testing.T) {
+	suite.Run(t, new(GoogleOauthClientTest))
+}
+
+func (t *GoogleOauthClientTest) SetupTest() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t.T(), err)
+	t.key = key
+
+	t.claims = &googleIdTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://accounts.google.com",
+			Subject:   "1234567890",
+			Audience:  jwt.ClaimStrings{"client-id"},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Email:         "6430000021@student.chula.ac.th",
+		EmailVerified: true,
+		HostedDomain:  "student.chula.ac.th",
+		Nonce:         "nonce",
+		GivenName:     "John",
+		FamilyName:    "Doe",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "valid-code" || r.FormValue("code_verifier") != "code-verifier" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     t.idToken,
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_ = json.NewEncoder(w).Encode(dto.Jwks{Keys: []*dto.Jwk{{
+			Kty: "RSA",
+			Kid: "google-key",
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(t.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(t.key.E)).Bytes()),
+		}}})
+	})
+	t.server = httptest.NewServer(mux)
+
+	t.client = NewGoogleOauthClient(&oauth2.Config{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Endpoint:     oauth2.Endpoint{TokenURL: t.server.URL + "/token"},
+	}, "")
+	t.client.httpClient = t.server.Client()
+	t.client.jwks = NewJwksCache(t.server.URL+"/certs", t.server.Client())
+}
+
+func (t *GoogleOauthClientTest) TearDownTest() {
+	t.server.Close()
+}
+
+func (t *GoogleOauthClientTest) sign(key *rsa.PrivateKey, kid string) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, t.claims)
+	token.Header["kid"] = kid
+
+	idToken, err := token.SignedString(key)
+	assert.Nil(t.T(), err)
+
+	t.idToken = idToken
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailSuccess() {
+	want := &GoogleUserEmailResponse{
+		Subject:       "1234567890",
+		Email:         "6430000021@student.chula.ac.th",
+		EmailVerified: true,
+		Firstname:     "John",
+		Lastname:      "Doe",
+	}
+
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidCode() {
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("invalid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidCode, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidCodeVerifier() {
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "another-code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidCode, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidNonce() {
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "another-nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestAuthCodeURL() {
+	authUrl, err := url.Parse(t.client.AuthCodeURL("state", "nonce", "code-challenge"))
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "state", authUrl.Query().Get("state"))
+	assert.Equal(t.T(), "nonce", authUrl.Query().Get("nonce"))
+	assert.Equal(t.T(), "code-challenge", authUrl.Query().Get("code_challenge"))
+	assert.Equal(t.T(), "S256", authUrl.Query().Get("code_challenge_method"))
+	assert.Equal(t.T(), "student.chula.ac.th", authUrl.Query().Get("hd"))
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidSignature() {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t.T(), err)
+
+	t.sign(otherKey, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailUnknownKey() {
+	t.sign(t.key, "rotated-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidAudience() {
+	t.claims.Audience = jwt.ClaimStrings{"another-client-id"}
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailInvalidIssuer() {
+	t.claims.Issuer = "https://evil.example.com"
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailExpired() {
+	t.claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), InvalidIdToken, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailUnverifiedEmail() {
+	t.claims.EmailVerified = false
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ForbiddenDomain, err)
+}
+
+func (t *GoogleOauthClientTest) TestGetUserEmailForbiddenHostedDomain() {
+	t.claims.HostedDomain = ""
+	t.claims.Email = "6430000021@gmail.com"
+	t.sign(t.key, "google-key")
+
+	actual, err := t.client.GetUserEmail("valid-code", "code-verifier", "nonce")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ForbiddenDomain, err)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultJwksTTL      = time.Hour
+	minJwksRefreshDelay = time.Minute
+)
+
+var maxAgePattern = regexp.MustCompile(`max-age=(\d+)`)
+
+// JwksCache keeps the RSA keys of a remote JWKS, the keys are fetched again when the Cache-Control max-age expires
+// or when an unknown key id shows up (at most once a minute)
+type JwksCache struct {
+	url        string
+	httpClient *http.Client
+	mu         sync.Mutex
+	keys       map[string]*rsa.PublicKey
+	fetchedAt  time.Time
+	expiresAt  time.Time
+	now        func() time.Time
+}
+
+func NewJwksCache(url string, httpClient *http.Client) *JwksCache {
+	return &JwksCache{
+		url:        url,
+		httpClient: httpClient,
+		keys:       map[string]*rsa.PublicKey{},
+		now:        time.Now,
+	}
+}
+
+func (c *JwksCache) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	key, ok := c.keys[kid]
+	if ok && now.Before(c.expiresAt) {
+		return key, nil
+	}
+
+	if ok || now.Sub(c.fetchedAt) >= minJwksRefreshDelay {
+		if err := c.refresh(now); err != nil {
+			return nil, err
+		}
+	}
+
+	key, ok = c.keys[kid]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown key id %q", kid))
+	}
+
+	return key, nil
+}
+
+func (c *JwksCache) refresh(now time.Time) error {
+	resp, err := c.httpClient.Get(c.url)
+	if err != nil {
+		return errors.Wrap(err, "error occurs while fetching the jwks")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("unexpected jwks status %v", resp.Status))
+	}
+
+	jwks := dto.Jwks{}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return errors.Wrap(err, "error occurs while decoding the jwks")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		key, err := parseRSAJwk(jwk)
+		if err != nil {
+			return err
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	ttl := defaultJwksTTL
+	if found := maxAgePattern.FindStringSubmatch(resp.Header.Get("Cache-Control")); found != nil {
+		if maxAge, err := strconv.Atoi(found[1]); err == nil {
+			ttl = time.Duration(maxAge) * time.Second
+		}
+	}
+
+	c.keys = keys
+	c.fetchedAt = now
+	c.expiresAt = now.Add(ttl)
+
+	return nil
+}
+
+func parseRSAJwk(jwk *dto.Jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid modulus of the key %v", jwk.Kid)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid exponent of the key %v", jwk.Kid)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+var defaultOidcScopes = []string{"openid", "profile", "email"}
+
+type OidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type OidcClient struct {
+	name        string
+	discovery   *OidcDiscovery
+	oauthConfig *oauth2.Config
+	httpClient  *http.Client
+	jwks        *JwksCache
+}
+
+type oidcIdTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce string `json:"nonce"`
+}
+
+// NewOidcClient reads the endpoints from the discovery document of the issuer
+func NewOidcClient(conf cfgldr.OidcProvider, httpClient *http.Client) (*OidcClient, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	discovery, err := fetchDiscovery(httpClient, conf.IssuerUrl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error occurs while loading the discovery document of %v", conf.Name)
+	}
+
+	scopes := conf.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOidcScopes
+	}
+
+	return &OidcClient{
+		name:      conf.Name,
+		discovery: discovery,
+		oauthConfig: &oauth2.Config{
+			ClientID:     conf.ClientID,
+			ClientSecret: conf.ClientSecret,
+			RedirectURL:  conf.RedirectUri,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  discovery.AuthorizationEndpoint,
+				TokenURL: discovery.TokenEndpoint,
+			},
+		},
+		httpClient: httpClient,
+		jwks:       NewJwksCache(discovery.JwksUri, httpClient),
+	}, nil
+}
+
+func fetchDiscovery(httpClient *http.Client, issuerUrl string) (*OidcDiscovery, error) {
+	issuer := strings.TrimSuffix(issuerUrl, "/")
+
+	resp, err := httpClient.Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("unexpected status %v", resp.Status))
+	}
+
+	discovery := &OidcDiscovery{}
+	if err := json.NewDecoder(resp.Body).Decode(discovery); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, errors.New(fmt.Sprintf("issuer mismatch, got %v", discovery.Issuer))
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" || discovery.JwksUri == "" {
+		return nil, errors.New("missing authorization, token, userinfo or jwks endpoint")
+	}
+
+	return discovery, nil
+}
+
+func (c *OidcClient) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	return c.oauthConfig.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// GetClaims exchanges the authorization code with the PKCE verifier, verifies the id token against the nonce of the login
+// and returns the claims from the userinfo endpoint of the same subject
+func (c *OidcClient) GetClaims(code string, codeVerifier string, nonce string) (map[string]interface{}, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, c.httpClient)
+
+	token, err := c.oauthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		log.Error().Err(err).Str("provider", c.name).Msg("Unable to exchange oauth token")
+		return nil, InvalidCode
+	}
+
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		log.Error().Str("provider", c.name).Msg("Identity provider did not return an id token")
+		return nil, InvalidIdToken
+	}
+
+	idTokenClaims, err := c.verifyIdToken(idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, HttpError
+	}
+	token.SetAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Str("provider", c.name).Msg("Unable to get user info")
+		return nil, HttpError
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Error().Str("provider", c.name).Str("status", resp.Status).Msg("Unable to get user info")
+		return nil, HttpError
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		log.Error().Err(err).Str("provider", c.name).Msg("Identity provider sent unexpected response")
+		return nil, InvalidFormat
+	}
+
+	// the userinfo response is only trusted for the subject of the verified id token
+	if subject, _ := claims["sub"].(string); subject != idTokenClaims.Subject {
+		log.Error().Str("provider", c.name).Msg("User info belongs to another subject")
+		return nil, InvalidIdToken
+	}
+
+	return claims, nil
+}
+
+func (c *OidcClient) verifyIdToken(idToken string, nonce string) (*oidcIdTokenClaims, error) {
+	claims := &oidcIdTokenClaims{}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.jwks.Key(kid)
+	})
+	if err != nil {
+		log.Error().Err(err).Str("provider", c.name).Msg("Unable to verify the id token")
+		return nil, InvalidIdToken
+	}
+
+	if !claims.VerifyAudience(c.oauthConfig.ClientID, true) {
+		log.Error().Str("provider", c.name).Strs("aud", claims.Audience).Msg("Id token is issued for another client")
+		return nil, InvalidIdToken
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(c.discovery.Issuer, "/") {
+		log.Error().Str("provider", c.name).Str("iss", claims.Issuer).Msg("Id token has an unexpected issuer")
+		return nil, InvalidIdToken
+	}
+
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		log.Error().Str("provider", c.name).Msg("Id token has an unexpected nonce")
+		return nil, InvalidIdToken
+	}
+
+	if claims.ExpiresAt == nil {
+		log.Error().Str("provider", c.name).Msg("Id token has no expiry")
+		return nil, InvalidIdToken
+	}
+
+	if claims.Subject == "" {
+		log.Error().Str("provider", c.name).Msg("Id token has no subject")
+		return nil, InvalidIdToken
+	}
+
+	return claims, nil
+}
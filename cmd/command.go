@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	ar "github.com/isd-sgcu/rpkm66-auth/pkg/repository/auth"
+	pr "github.com/isd-sgcu/rpkm66-auth/pkg/repository/permission"
+	ps "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// runCommand runs the one-off operation given on the command line instead of starting the server
+func runCommand(args []string, db *gorm.DB) error {
+	switch args[0] {
+	case "seed":
+		return ps.NewService(pr.NewRepository(db), 0).SeedDefaults()
+
+	case "import-role-grants":
+		if len(args) != 2 {
+			return errors.New("usage: import-role-grants <file.csv>")
+		}
+
+		return importRoleGrants(args[1], ar.NewRepository(db))
+
+	case "create-service-client":
+		if len(args) < 3 {
+			return errors.New("usage: create-service-client <client_id> <scope>...")
+		}
+
+		return createServiceClient(args[1], args[2:], ar.NewRepository(db))
+
+	default:
+		return errors.New(fmt.Sprintf("unknown command %v", args[0]))
+	}
+}
+
+func importRoleGrants(path string, repo ar.Repository) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	grants, err := utils.ParseRoleGrants(file)
+	if err != nil {
+		return err
+	}
+
+	if len(grants) == 0 {
+		return errors.New("No role grant is provided")
+	}
+
+	var roleGrants []*entity.RoleGrant
+	for _, grant := range grants {
+		roleGrants = append(roleGrants, &entity.RoleGrant{
+			StudentID: grant.StudentID,
+			Role:      grant.Role,
+			GrantedBy: "cli",
+		})
+	}
+
+	err = repo.UpsertRoleGrants(&roleGrants)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "import role grants").
+		Str("event", "role_grant_import").
+		Str("file", path).
+		Int("count", len(roleGrants)).
+		Msg("Import the role grants")
+
+	return nil
+}
+
+// createServiceClient prints the secret once, only its hash is stored
+func createServiceClient(clientId string, scopes []string, repo ar.Repository) error {
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		return err
+	}
+
+	client := &entity.ServiceClient{
+		ClientID:   clientId,
+		SecretHash: utils.Hash([]byte(secret)),
+		Scopes:     strings.Join(utils.ParseScopes(strings.Join(scopes, " ")), " "),
+	}
+
+	err = repo.CreateServiceClient(client)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "create service client").
+		Str("event", "service_client_create").
+		Str("client_id", clientId).
+		Str("scope", client.Scopes).
+		Msg("Create the service client")
+
+	fmt.Printf("client_id: %v\nclient_secret: %v\n", clientId, secret)
+
+	return nil
+}
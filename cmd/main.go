@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -14,10 +15,34 @@ import (
 	"github.com/isd-sgcu/rpkm66-auth/client"
 	"github.com/isd-sgcu/rpkm66-auth/database"
 	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	oc "github.com/isd-sgcu/rpkm66-auth/pkg/client/oidc"
+	jh "github.com/isd-sgcu/rpkm66-auth/pkg/handler/jwks"
+	ai "github.com/isd-sgcu/rpkm66-auth/pkg/interceptor/auth"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/mailer"
+	fm "github.com/isd-sgcu/rpkm66-auth/pkg/mailer/file"
+	sm "github.com/isd-sgcu/rpkm66-auth/pkg/mailer/smtp"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+	csp "github.com/isd-sgcu/rpkm66-auth/pkg/provider/chula_sso"
+	ep "github.com/isd-sgcu/rpkm66-auth/pkg/provider/email"
+	gp "github.com/isd-sgcu/rpkm66-auth/pkg/provider/google"
+	op "github.com/isd-sgcu/rpkm66-auth/pkg/provider/oidc"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+	fp "github.com/isd-sgcu/rpkm66-auth/pkg/publisher/file"
+	qp "github.com/isd-sgcu/rpkm66-auth/pkg/publisher/queue"
+	rp "github.com/isd-sgcu/rpkm66-auth/pkg/publisher/redis"
+	wp "github.com/isd-sgcu/rpkm66-auth/pkg/publisher/webhook"
+	aur "github.com/isd-sgcu/rpkm66-auth/pkg/repository/audit"
 	ar "github.com/isd-sgcu/rpkm66-auth/pkg/repository/auth"
 	"github.com/isd-sgcu/rpkm66-auth/pkg/repository/cache"
+	pr "github.com/isd-sgcu/rpkm66-auth/pkg/repository/permission"
+	aus "github.com/isd-sgcu/rpkm66-auth/pkg/service/audit"
 	as "github.com/isd-sgcu/rpkm66-auth/pkg/service/auth"
+	es "github.com/isd-sgcu/rpkm66-auth/pkg/service/eligibility"
 	js "github.com/isd-sgcu/rpkm66-auth/pkg/service/jwt"
+	mls "github.com/isd-sgcu/rpkm66-auth/pkg/service/magic_link"
+	ps "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	sts "github.com/isd-sgcu/rpkm66-auth/pkg/service/state"
 	ts "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
 	"github.com/isd-sgcu/rpkm66-auth/pkg/service/user"
 	jsg "github.com/isd-sgcu/rpkm66-auth/pkg/strategy"
@@ -105,6 +130,18 @@ func main() {
 			Msg("Failed to start service")
 	}
 
+	if len(os.Args) > 1 {
+		err = runCommand(os.Args[1:], db)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msgf("Failed to run %v", os.Args[1])
+		}
+
+		return
+	}
+
 	cacheDB, err := database.InitRedisConnect(&conf.Redis)
 	if err != nil {
 		log.Fatal().
@@ -129,28 +166,201 @@ func main() {
 			Msg("Cannot connect to service")
 	}
 
-	grpcServer := grpc.NewServer()
-
 	cSSO := client.NewChulaSSO(conf.ChulaSSO)
-	gClient := client.NewGoogleOauthClient(oauthConfig)
+	gClient := client.NewGoogleOauthClient(oauthConfig, conf.Oauth.HostedDomain)
+
+	providers := []provider.IdentityProvider{csp.NewProvider(cSSO), gp.NewProvider(gClient)}
+
+	for _, oidcConf := range conf.Oidc {
+		for _, p := range providers {
+			if string(p.Name()) == oidcConf.Name {
+				log.Fatal().
+					Str("service", "auth").
+					Str("provider", oidcConf.Name).
+					Msg("Duplicate identity provider name")
+			}
+		}
+
+		oidcClient, err := oc.NewOidcClient(oidcConf, nil)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Str("provider", oidcConf.Name).
+				Msg("Failed to load the oidc provider")
+		}
+
+		oidcProvider, err := op.NewProvider(oidcClient, oidcConf)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Str("provider", oidcConf.Name).
+				Msg("Failed to load the oidc provider")
+		}
+
+		providers = append(providers, oidcProvider)
+	}
 
 	cacheRepo := cache.NewRepository(cacheDB)
 
+	if conf.MagicLink.Url != "" {
+		var mlr mailer.Mailer
+
+		switch conf.Mail.Driver {
+		case "smtp":
+			mlr, err = sm.NewMailer(conf.Mail)
+		case "file":
+			mlr, err = fm.NewMailer(conf.Mail.Path, conf.App.Debug)
+		case "":
+			err = fmt.Errorf("missing mail driver, set driver under [mail] to smtp or file")
+		default:
+			err = fmt.Errorf("unknown mail driver %q", conf.Mail.Driver)
+		}
+
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msg("Failed to load the mailer")
+		}
+
+		mlSrv := mls.NewService(cacheRepo, conf.MagicLink.TTL, conf.MagicLink.Cooldown)
+
+		emailProvider, err := ep.NewProvider(mlSrv, mlr, conf.MagicLink)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msg("Failed to load the email provider")
+		}
+
+		providers = append(providers, emailProvider)
+	}
+
 	usrClient := user_proto.NewUserServiceClient(backendConn)
 	usrSrv := user.NewUserService(usrClient)
 
-	stg := jsg.NewJwtStrategy(conf.Jwt.Secret)
+	stg, err := jsg.NewJwtStrategy(conf.Jwt)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the jwt signing key")
+	}
+
 	jtSrv := js.NewJwtService(conf.Jwt, stg)
 
 	tkSrv := ts.NewTokenService(jtSrv, cacheRepo)
 
+	stSrv := sts.NewService(cacheRepo, conf.App.LoginStateTTL)
+
+	pRepo := pr.NewRepository(db)
+	pSrv := ps.NewService(pRepo, conf.App.PermissionRefresh)
+
+	err = pSrv.SeedDefaults()
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to seed the default permissions")
+	}
+
+	err = pSrv.Load()
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the permissions")
+	}
+
+	pSrv.Start()
+
+	academicYear, err := utils.NewAcademicYear(conf.App.AcademicYearStart, conf.App.AcademicYear, time.Now)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the academic year")
+	}
+
+	clientInfo, err := utils.NewClientInfo(conf.App.TrustedProxies)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the trusted proxies")
+	}
+
+	eSrv, err := es.NewService(conf.Eligibility, academicYear)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the eligibility policy")
+	}
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(ai.NewUnaryInterceptor(tkSrv, pSrv)))
+
+	var auPub publisher.EventPublisher
+
+	switch conf.Audit.Publisher {
+	case "redis":
+		auPub = rp.NewPublisher(cacheDB, conf.Audit.Stream, conf.Audit.StreamMaxLen)
+	case "webhook":
+		auPub, err = wp.NewPublisher(conf.Audit.WebhookUrl, conf.Audit.WebhookSecret)
+	case "file":
+		auPub, err = fp.NewPublisher(conf.Audit.Path)
+	case "":
+	default:
+		err = fmt.Errorf("unknown audit publisher %q", conf.Audit.Publisher)
+	}
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("service", "auth").
+			Msg("Failed to load the audit publisher")
+	}
+
+	var auQueue publisher.AsyncPublisher
+	if auPub != nil {
+		auQueue = qp.NewPublisher(auPub, conf.Audit.QueueSize, conf.Audit.MaxRetries)
+		auPub = auQueue
+	}
+
+	auRepo := aur.NewRepository(db)
+	auSrv := aus.NewService(auRepo, auPub, clientInfo)
+
 	aRepo := ar.NewRepository(db)
-	aSrv := as.NewService(aRepo, cSSO, tkSrv, usrSrv, conf.App, oauthConfig, gClient)
+	aSrv := as.NewService(aRepo, providers, tkSrv, usrSrv, pSrv, eSrv, stSrv, auSrv, clientInfo, academicYear, conf.App)
 
 	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
 	auth_proto.RegisterAuthServiceServer(grpcServer, aSrv)
 
 	reflection.Register(grpcServer)
+
+	mux := http.NewServeMux()
+	mux.Handle(jh.Path, jh.NewHandler(tkSrv))
+
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%v", conf.App.HttpPort),
+		Handler: mux,
+	}
+
+	go func() {
+		log.Info().
+			Str("service", "auth").
+			Msgf("rpkm66 auth http starting at port %v", conf.App.HttpPort)
+
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal().
+				Err(err).
+				Str("service", "auth").
+				Msg("Failed to start http server")
+		}
+	}()
+
 	go func() {
 		log.Info().
 			Str("service", "auth").
@@ -174,9 +384,23 @@ func main() {
 		},
 		"server": func(ctx context.Context) error {
 			grpcServer.GracefulStop()
+			pSrv.Close()
 			return nil
 		},
+		"http server": func(ctx context.Context) error {
+			return httpServer.Shutdown(ctx)
+		},
 		"cache": func(ctx context.Context) error {
+			// the audit queue can still be adding to the redis stream
+			if auQueue != nil {
+				if err := auQueue.Close(ctx); err != nil {
+					log.Error().
+						Err(err).
+						Str("service", "auth").
+						Msg("Failed to deliver the queued audit events")
+				}
+			}
+
 			return cacheDB.Close()
 		},
 	})
@@ -0,0 +1,18 @@
+package auth
+
+type AuditEvent string
+
+const (
+	LOGIN_SUCCESS       AuditEvent = "login_success"
+	LOGIN_FAILURE                  = "login_failure"
+	TOKEN_REFRESH                  = "token_refresh"
+	REFRESH_REUSE                  = "refresh_reuse"
+	LOGOUT                         = "logout"
+	SESSION_REVOKE                 = "session_revoke"
+	ROLE_CHANGE                    = "role_change"
+	ROLE_GRANT_IMPORT              = "role_grant_import"
+	IDENTITY_LINK                  = "identity_link"
+	IDENTITY_UNLINK                = "identity_unlink"
+	ACCOUNT_CREATE                 = "account_create"
+	IMPERSONATION_START            = "impersonation_start"
+)
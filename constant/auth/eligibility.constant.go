@@ -0,0 +1,14 @@
+package auth
+
+const ELIGIBILITY_DOMAIN = "auth.rpkm66.isd-sgcu"
+
+type DenialReason string
+
+const (
+	STUDENT_ID_DENIED    DenialReason = "STUDENT_ID_DENIED"
+	INVALID_STUDENT_ID                = "INVALID_STUDENT_ID"
+	OUTSIDE_LOGIN_WINDOW              = "OUTSIDE_LOGIN_WINDOW"
+	FACULTY_DENIED                    = "FACULTY_DENIED"
+	FACULTY_NOT_ALLOWED               = "FACULTY_NOT_ALLOWED"
+	YEAR_NOT_ALLOWED                  = "YEAR_NOT_ALLOWED"
+)
@@ -0,0 +1,50 @@
+package auth
+
+type Permission string
+
+const (
+	SESSION_MANAGE   Permission = "session:manage"
+	USER_READ                   = "user:read"
+	USER_CHECKIN                = "user:checkin"
+	BAAN_READ                   = "baan:read"
+	BAAN_MANAGE                 = "baan:manage"
+	EVENT_MANAGE                = "event:manage"
+	ROLE_READ                   = "role:read"
+	ROLE_WRITE                  = "role:write"
+	IDENTITY_MANAGE             = "identity:manage"
+	USER_IMPERSONATE            = "user:impersonate"
+	AUDIT_READ                  = "audit:read"
+)
+
+// DEFAULT_PERMISSIONS seeds the role_permissions table when it is empty, the table is the source of truth afterwards
+var DEFAULT_PERMISSIONS = map[Role][]Permission{
+	USER: {
+		SESSION_MANAGE,
+	},
+	BAAN_STAFF: {
+		SESSION_MANAGE,
+		USER_READ,
+		USER_CHECKIN,
+		BAAN_READ,
+	},
+	EVENT_STAFF: {
+		SESSION_MANAGE,
+		USER_READ,
+		USER_CHECKIN,
+		BAAN_READ,
+		EVENT_MANAGE,
+	},
+	ADMIN: {
+		SESSION_MANAGE,
+		USER_READ,
+		USER_CHECKIN,
+		BAAN_READ,
+		BAAN_MANAGE,
+		EVENT_MANAGE,
+		ROLE_READ,
+		ROLE_WRITE,
+		IDENTITY_MANAGE,
+		USER_IMPERSONATE,
+		AUDIT_READ,
+	},
+}
@@ -0,0 +1,9 @@
+package auth
+
+type Provider string
+
+const (
+	CHULA_SSO Provider = "chula_sso"
+	GOOGLE             = "google"
+	EMAIL              = "email"
+)
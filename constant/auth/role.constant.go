@@ -8,3 +8,5 @@ const (
 	BAAN_STAFF       = "baan_staff"
 	USER             = "user"
 )
+
+var ROLES = []Role{ADMIN, EVENT_STAFF, BAAN_STAFF, USER}
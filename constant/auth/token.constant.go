@@ -0,0 +1,5 @@
+package auth
+
+const (
+	BEARER = "Bearer"
+)
@@ -5,7 +5,9 @@ import (
 	"strconv"
 
 	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
 	"github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -17,7 +19,7 @@ func InitDatabase(conf *cfgldr.Database) (db *gorm.DB, err error) {
 		DSN: dsn,
 	}), &gorm.Config{})
 
-	err = db.AutoMigrate(auth.Auth{})
+	err = db.AutoMigrate(auth.Auth{}, auth.Session{}, auth.RefreshToken{}, auth.RoleGrant{}, auth.Identity{}, auth.ServiceClient{}, permission.RolePermission{}, audit.AuditEvent{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,30 @@
+package auth
+
+import "time"
+
+// AuditFilter matches every event when a field is left empty, the page starts at 1
+type AuditFilter struct {
+	Event     string
+	UserID    string
+	StudentID string
+	Provider  string
+	ActorID   string
+	Since     time.Time
+	Until     time.Time
+	Page      int
+	PageSize  int
+}
+
+// AuditMessage is the audit event streamed to the external sinks
+type AuditMessage struct {
+	ID        string    `json:"id,omitempty"`
+	Event     string    `json:"event"`
+	UserID    string    `json:"user_id,omitempty"`
+	StudentID string    `json:"student_id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -21,15 +21,82 @@ type ChulaSSOCredential struct {
 
 type TokenPayloadAuth struct {
 	jwt.RegisteredClaims
-	UserId string `json:"user_id"`
+	UserId    string `json:"user_id"`
+	SessionId string `json:"session_id"`
+	Role      string `json:"role,omitempty"`
+	StudentId string `json:"student_id,omitempty"`
+	Faculty   string `json:"faculty,omitempty"`
+	Year      string `json:"year,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	Act       *Actor `json:"act,omitempty"`
 }
 
+// Actor is the act claim of RFC 8693, it names the admin who acts as the user of an impersonation token
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+type TokenPayloadService struct {
+	jwt.RegisteredClaims
+	ClientId string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// UserCredential is the caller of a token, a service token has the client id and scopes instead of the user, session and role
 type UserCredential struct {
-	UserId string    `json:"user_id"`
-	Role   auth.Role `json:"role"`
+	UserId    string    `json:"user_id"`
+	SessionId string    `json:"session_id"`
+	Role      auth.Role `json:"role"`
+	ClientId  string    `json:"client_id,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+}
+
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionId string `json:"session_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Actor     string `json:"act,omitempty"`
+}
+
+type RoleGrant struct {
+	StudentID string `json:"student_id"`
+	Role      string `json:"role"`
+}
+
+// LoginState is kept in the cache between the login url and the callback of a redirect login
+type LoginState struct {
+	State        string        `json:"state"`
+	Nonce        string        `json:"nonce"`
+	CodeVerifier string        `json:"code_verifier"`
+	Provider     auth.Provider `json:"provider"`
 }
 
 type CacheAuth struct {
 	Token string    `json:"token"`
 	Role  auth.Role `json:"role"`
+	Actor string    `json:"actor,omitempty"`
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type Jwks struct {
+	Keys []*Jwk `json:"keys"`
 }
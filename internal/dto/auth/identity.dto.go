@@ -0,0 +1,16 @@
+package auth
+
+import "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+
+// Identity is the provider independent result of a successful login proof,
+// the student id is empty when the account does not belong to a student, such an identity is still valid but it can only login once an admin has linked it.
+// EmailVerified tells whether the provider proved the email belongs to the user, only then can the email claim a link made by email
+type Identity struct {
+	Subject       string        `json:"subject"`
+	StudentID     string        `json:"student_id"`
+	Firstname     string        `json:"firstname"`
+	Lastname      string        `json:"lastname"`
+	Email         string        `json:"email"`
+	EmailVerified bool          `json:"email_verified"`
+	Provider      auth.Provider `json:"provider"`
+}
@@ -0,0 +1,12 @@
+package auth
+
+type Mail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// MagicLink is kept in the cache under the hash of its token until it is redeemed or expired
+type MagicLink struct {
+	Email string `json:"email"`
+}
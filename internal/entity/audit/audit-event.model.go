@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+// AuditEvent is an append only record of an authentication event, the actor is the admin when it is not the user themselves
+type AuditEvent struct {
+	entity.Base
+	Event     string `json:"event" gorm:"type:text;index"`
+	UserID    string `json:"user_id" gorm:"type:text;index"`
+	StudentID string `json:"student_id" gorm:"type:text;index"`
+	Provider  string `json:"provider" gorm:"type:text"`
+	ActorID   string `json:"actor_id" gorm:"type:text;index"`
+	Detail    string `json:"detail" gorm:"type:text"`
+	ClientIP  string `json:"client_ip" gorm:"type:text"`
+	UserAgent string `json:"user_agent" gorm:"type:text"`
+}
@@ -6,7 +6,9 @@ import (
 
 type Auth struct {
 	entity.Base
-	UserID       string `json:"user_id" gorm:"index:,unique"`
-	Role         string `json:"role" gorm:"type:text"`
-	RefreshToken string `json:"refresh_token" gorm:"index"`
+	UserID    string `json:"user_id" gorm:"index:,unique"`
+	Role      string `json:"role" gorm:"type:text"`
+	StudentID string `json:"student_id" gorm:"type:text"`
+	Faculty   string `json:"faculty" gorm:"type:text"`
+	Year      string `json:"year" gorm:"type:text"`
 }
@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"github.com/google/uuid"
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+// Identity links an account of an identity provider to the auth, an identity linked by email has no subject until its first login
+type Identity struct {
+	entity.Base
+	Provider string    `json:"provider" gorm:"type:text;uniqueIndex:idx_identity_provider_subject,where:subject <> ''"`
+	Subject  string    `json:"subject" gorm:"type:text;uniqueIndex:idx_identity_provider_subject,where:subject <> ''"`
+	Email    string    `json:"email" gorm:"type:text;index"`
+	AuthID   uuid.UUID `json:"auth_id" gorm:"type:uuid;index"`
+	LinkedBy string    `json:"linked_by" gorm:"type:text"`
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+// RefreshToken is a member of the token family of a session, each refresh rotates the token into a new child
+type RefreshToken struct {
+	entity.Base
+	SessionID uuid.UUID  `json:"session_id" gorm:"index"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Token     string     `json:"token" gorm:"index:,unique"`
+	RotatedAt *time.Time `json:"rotated_at" gorm:"type:timestamp"`
+}
@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+// RoleGrant assigns the role to a student before the auth is created on the first login
+type RoleGrant struct {
+	entity.Base
+	StudentID string `json:"student_id" gorm:"index:,unique"`
+	Role      string `json:"role" gorm:"type:text"`
+	GrantedBy string `json:"granted_by" gorm:"type:text"`
+}
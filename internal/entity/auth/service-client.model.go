@@ -0,0 +1,11 @@
+package auth
+
+import "github.com/isd-sgcu/rpkm66-auth/internal/entity"
+
+// ServiceClient is a backend that gets its token with the client credentials grant, only the hash of the secret is kept
+type ServiceClient struct {
+	entity.Base
+	ClientID   string `json:"client_id" gorm:"type:text;uniqueIndex"`
+	SecretHash string `json:"secret_hash" gorm:"type:text"`
+	Scopes     string `json:"scopes" gorm:"type:text"`
+}
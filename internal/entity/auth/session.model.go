@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+type Session struct {
+	entity.Base
+	UserID           string     `json:"user_id" gorm:"index"`
+	Provider         string     `json:"provider" gorm:"type:text"`
+	IPAddress        string     `json:"ip_address" gorm:"type:text"`
+	UserAgent        string     `json:"user_agent" gorm:"type:text"`
+	LastRefreshedAt  *time.Time `json:"last_refreshed_at" gorm:"type:timestamp"`
+	RefreshExpiresAt time.Time  `json:"refresh_expires_at" gorm:"type:timestamp"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"type:timestamp"`
+}
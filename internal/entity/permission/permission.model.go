@@ -0,0 +1,11 @@
+package permission
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+)
+
+type RolePermission struct {
+	entity.Base
+	Role       string `json:"role" gorm:"type:text;index:idx_role_permission,unique"`
+	Permission string `json:"permission" gorm:"type:text;index:idx_role_permission,unique"`
+}
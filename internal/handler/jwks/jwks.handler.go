@@ -0,0 +1,36 @@
+package jwks
+
+import (
+	"encoding/json"
+	"net/http"
+
+	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
+	"github.com/rs/zerolog/log"
+)
+
+type Handler struct {
+	tokenService token_svc.Service
+}
+
+func NewHandler(tokenService token_svc.Service) *Handler {
+	return &Handler{tokenService: tokenService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	err := json.NewEncoder(w).Encode(h.tokenService.GetJwks())
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "jwks").
+			Msg("Error while writing the jwks")
+	}
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	permission_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Policy describes who can call a method, a policy without role and permission allows any authenticated user
+type Policy struct {
+	Public     bool
+	Role       role.Role
+	Permission role.Permission
+}
+
+var (
+	PUBLIC        = Policy{Public: true}
+	AUTHENTICATED = Policy{}
+)
+
+// DefaultPolicies lists every method served by the server, a method that is not listed is always rejected
+var DefaultPolicies = map[string]Policy{
+	grpc_health_v1.Health_Check_FullMethodName:              PUBLIC,
+	auth_proto.AuthService_VerifyTicket_FullMethodName:      PUBLIC,
+	auth_proto.AuthService_Validate_FullMethodName:          PUBLIC,
+	auth_proto.AuthService_RefreshToken_FullMethodName:      PUBLIC,
+	auth_proto.AuthService_IssueServiceToken_FullMethodName: PUBLIC,
+	auth_proto.AuthService_GetGoogleLoginUrl_FullMethodName: PUBLIC,
+	auth_proto.AuthService_VerifyGoogleLogin_FullMethodName: PUBLIC,
+	auth_proto.AuthService_GetOidcLoginUrl_FullMethodName:   PUBLIC,
+	auth_proto.AuthService_VerifyOidcLogin_FullMethodName:   PUBLIC,
+	auth_proto.AuthService_SendMagicLink_FullMethodName:     PUBLIC,
+	auth_proto.AuthService_VerifyMagicLink_FullMethodName:   PUBLIC,
+	auth_proto.AuthService_GetJwks_FullMethodName:           PUBLIC,
+	auth_proto.AuthService_Introspect_FullMethodName:        PUBLIC,
+	auth_proto.AuthService_Logout_FullMethodName:            AUTHENTICATED,
+	auth_proto.AuthService_ListSessions_FullMethodName:      AUTHENTICATED,
+	auth_proto.AuthService_RevokeSession_FullMethodName:     AUTHENTICATED,
+	auth_proto.AuthService_CheckPermission_FullMethodName:   AUTHENTICATED,
+	auth_proto.AuthService_GetUserRole_FullMethodName:       {Permission: role.ROLE_READ},
+	auth_proto.AuthService_SetUserRole_FullMethodName:       {Permission: role.ROLE_WRITE},
+	auth_proto.AuthService_ImportRoleGrants_FullMethodName:  {Permission: role.ROLE_WRITE},
+	auth_proto.AuthService_LinkIdentity_FullMethodName:      {Permission: role.IDENTITY_MANAGE},
+	auth_proto.AuthService_UnlinkIdentity_FullMethodName:    {Permission: role.IDENTITY_MANAGE},
+	auth_proto.AuthService_ListIdentities_FullMethodName:    {Permission: role.IDENTITY_MANAGE},
+	auth_proto.AuthService_CreateAccount_FullMethodName:     {Permission: role.IDENTITY_MANAGE},
+	auth_proto.AuthService_Impersonate_FullMethodName:       {Role: role.ADMIN, Permission: role.USER_IMPERSONATE},
+	auth_proto.AuthService_ListAuditEvents_FullMethodName:   {Permission: role.AUDIT_READ},
+}
+
+// impersonationDenied lists the permissions an impersonation credential cannot use even when the user holds them,
+// the admin behind it would otherwise change roles, links or read the audit log in the user's name
+var impersonationDenied = map[role.Permission]bool{
+	role.ROLE_WRITE:       true,
+	role.IDENTITY_MANAGE:  true,
+	role.USER_IMPERSONATE: true,
+	role.AUDIT_READ:       true,
+}
+
+// tokenRequest is implemented by the requests that carry the token in the body for the callers that do not send the metadata yet
+type tokenRequest interface {
+	GetToken() string
+}
+
+type Interceptor struct {
+	tokenService      token_svc.Service
+	permissionService permission_svc.Service
+	policies          map[string]Policy
+}
+
+func NewInterceptor(tokenService token_svc.Service, permissionService permission_svc.Service, policies map[string]Policy) *Interceptor {
+	return &Interceptor{
+		tokenService:      tokenService,
+		permissionService: permissionService,
+		policies:          policies,
+	}
+}
+
+func (i *Interceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	policy, ok := i.policies[info.FullMethod]
+	if !ok {
+		log.Warn().
+			Str("service", "auth").
+			Str("module", "interceptor").
+			Str("method", info.FullMethod).
+			Msg("Someone is trying to call the method without policy")
+		return nil, status.Error(codes.PermissionDenied, "Insufficient permission")
+	}
+
+	if policy.Public {
+		return handler(ctx, req)
+	}
+
+	token := utils.GetBearerToken(ctx)
+	if token == "" {
+		if r, ok := req.(tokenRequest); ok {
+			token = r.GetToken()
+		}
+	}
+
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "Missing token")
+	}
+
+	credential, err := i.tokenService.Validate(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	allowed := policy.Role == "" || credential.Role == policy.Role
+
+	// a service token has no session or role, it can only call the methods guarded by a permission it holds as a scope
+	if credential.ClientId != "" {
+		allowed = policy.Role == "" && policy.Permission != "" && utils.HasScope(credential.Scopes, string(policy.Permission))
+	} else if credential.Actor != "" && impersonationDenied[policy.Permission] {
+		allowed = false
+	} else if allowed && policy.Permission != "" {
+		allowed, err = i.permissionService.HasPermission(credential.Role, string(policy.Permission))
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("service", "auth").
+				Str("module", "interceptor").
+				Str("method", info.FullMethod).
+				Msg("Error while querying the permissions")
+			return nil, status.Error(codes.Internal, "Internal service error")
+		}
+	}
+
+	if !allowed {
+		log.Warn().
+			Str("service", "auth").
+			Str("module", "interceptor").
+			Str("method", info.FullMethod).
+			Str("user_id", credential.UserId).
+			Str("client_id", credential.ClientId).
+			Msg("Someone is trying to call the method without permission")
+		return nil, status.Error(codes.PermissionDenied, "Insufficient permission")
+	}
+
+	return handler(utils.NewCredentialContext(ctx, credential), req)
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/google/uuid"
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/auth"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type AuthInterceptorTest struct {
+	suite.Suite
+	UserCredential    *dto.UserCredential
+	AdminCredential   *dto.UserCredential
+	ServiceCredential *dto.UserCredential
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	suite.Run(t, new(AuthInterceptorTest))
+}
+
+func (t *AuthInterceptorTest) SetupTest() {
+	t.UserCredential = &dto.UserCredential{
+		UserId:    faker.UUIDDigit(),
+		SessionId: uuid.New().String(),
+		Role:      role.USER,
+	}
+
+	t.AdminCredential = &dto.UserCredential{
+		UserId:    faker.UUIDDigit(),
+		SessionId: uuid.New().String(),
+		Role:      role.ADMIN,
+	}
+
+	t.ServiceCredential = &dto.UserCredential{
+		ClientId: "checkin",
+		Scopes:   []string{string(role.ROLE_READ)},
+	}
+}
+
+func bearerContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// handler echoes the credential attached to the context
+func handler(ctx context.Context, _ interface{}) (interface{}, error) {
+	credential, _ := utils.GetCredential(ctx)
+
+	return credential, nil
+}
+
+func (t *AuthInterceptorTest) TestPublicMethod() {
+	tokenService := &mock.TokenServiceMock{}
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(context.Background(), &auth_proto.ValidateRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_Validate_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Nil(t.T(), actual.(*dto.UserCredential))
+	tokenService.AssertNumberOfCalls(t.T(), "Validate", 0)
+}
+
+func (t *AuthInterceptorTest) TestAuthenticatedMethod() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.ListSessionsRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_ListSessions_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserCredential, actual)
+}
+
+func (t *AuthInterceptorTest) TestAuthenticatedMethodTokenInBody() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(context.Background(), &auth_proto.LogoutRequest{Token: token}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_Logout_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserCredential, actual)
+}
+
+func (t *AuthInterceptorTest) TestMissingToken() {
+	tokenService := &mock.TokenServiceMock{}
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(context.Background(), &auth_proto.ListSessionsRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_ListSessions_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestInvalidToken() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.ListSessionsRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_ListSessions_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestPermissionMethod() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.AdminCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("HasPermission", t.AdminCredential.Role, string(role.ROLE_WRITE)).Return(true, nil)
+
+	interceptor := NewInterceptor(tokenService, permissionService, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.SetUserRoleRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_SetUserRole_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.AdminCredential, actual)
+}
+
+func (t *AuthInterceptorTest) TestPermissionMethodForbidden() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("HasPermission", t.UserCredential.Role, string(role.ROLE_WRITE)).Return(false, nil)
+
+	interceptor := NewInterceptor(tokenService, permissionService, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.SetUserRoleRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_SetUserRole_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestRoleMethodForbidden() {
+	token := faker.Word()
+	method := "/rpkm66.auth.auth.v1.AuthService/AdminOnly"
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, map[string]Policy{method: {Role: role.ADMIN}})
+
+	actual, err := interceptor.Unary(bearerContext(token), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestUnknownMethod() {
+	tokenService := &mock.TokenServiceMock{}
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/rpkm66.auth.auth.v1.AuthService/Unknown"}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestEveryMethodHasPolicy() {
+	for _, method := range auth_proto.AuthService_ServiceDesc.Methods {
+		_, ok := DefaultPolicies["/"+auth_proto.AuthService_ServiceDesc.ServiceName+"/"+method.MethodName]
+
+		assert.Truef(t.T(), ok, "missing policy for %v", method.MethodName)
+	}
+}
+
+func (t *AuthInterceptorTest) TestServiceTokenWithScope() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.ServiceCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+
+	interceptor := NewInterceptor(tokenService, permissionService, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.GetUserRoleRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_GetUserRole_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.ServiceCredential, actual)
+	permissionService.AssertNotCalled(t.T(), "HasPermission")
+}
+
+func (t *AuthInterceptorTest) TestServiceTokenWithoutScope() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.ServiceCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.SetUserRoleRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_SetUserRole_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestServiceTokenUserMethod() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.ServiceCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.ListSessionsRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_ListSessions_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+}
+
+func (t *AuthInterceptorTest) TestImpersonateRequiresAdminRole() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+
+	interceptor := NewInterceptor(tokenService, permissionService, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.ImpersonateRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_Impersonate_FullMethodName}, handler)
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	permissionService.AssertNotCalled(t.T(), "HasPermission")
+}
+
+func (t *AuthInterceptorTest) TestImpersonationAdminMethodForbidden() {
+	token := faker.Word()
+	t.UserCredential.Role = role.EVENT_STAFF
+	t.UserCredential.Actor = t.AdminCredential.UserId
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("HasPermission", t.UserCredential.Role, testify.Anything).Return(true, nil)
+
+	interceptor := NewInterceptor(tokenService, permissionService, DefaultPolicies)
+
+	for _, method := range []string{
+		auth_proto.AuthService_SetUserRole_FullMethodName,
+		auth_proto.AuthService_ImportRoleGrants_FullMethodName,
+		auth_proto.AuthService_LinkIdentity_FullMethodName,
+		auth_proto.AuthService_CreateAccount_FullMethodName,
+		auth_proto.AuthService_ListAuditEvents_FullMethodName,
+	} {
+		actual, err := interceptor.Unary(bearerContext(token), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+
+		st, ok := status.FromError(err)
+
+		assert.True(t.T(), ok)
+		assert.Nil(t.T(), actual)
+		assert.Equal(t.T(), codes.PermissionDenied, st.Code(), method)
+	}
+
+	permissionService.AssertNotCalled(t.T(), "HasPermission", testify.Anything, testify.Anything)
+}
+
+func (t *AuthInterceptorTest) TestImpersonationUserMethod() {
+	token := faker.Word()
+	t.UserCredential.Actor = t.AdminCredential.UserId
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	interceptor := NewInterceptor(tokenService, &mock.PermissionServiceMock{}, DefaultPolicies)
+
+	actual, err := interceptor.Unary(bearerContext(token), &auth_proto.ListSessionsRequest{}, &grpc.UnaryServerInfo{FullMethod: auth_proto.AuthService_ListSessions_FullMethodName}, handler)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.UserCredential, actual)
+}
@@ -0,0 +1,60 @@
+package file
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// mailerImpl appends every mail as a json line to the file for local testing, the mail is logged when there is no file in debug mode only since the body holds a live login link
+type mailerImpl struct {
+	path string
+	mu   sync.Mutex
+}
+
+type record struct {
+	dto.Mail
+	SentAt time.Time `json:"sent_at"`
+}
+
+func NewMailer(path string, debug bool) (*mailerImpl, error) {
+	if path == "" && !debug {
+		return nil, errors.New("missing path of the file mailer, the mail is only logged in debug mode")
+	}
+
+	return &mailerImpl{path: path}, nil
+}
+
+func (m *mailerImpl) Send(mail *dto.Mail) error {
+	if m.path == "" {
+		log.Info().
+			Str("service", "auth").
+			Str("module", "mailer").
+			Str("to", mail.To).
+			Str("subject", mail.Subject).
+			Msg(mail.Body)
+		return nil
+	}
+
+	line, err := json.Marshal(record{Mail: *mail, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error occurs while opening the mail file")
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return errors.Wrap(err, "error occurs while writing the mail file")
+}
@@ -0,0 +1,64 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FileMailerTest struct {
+	suite.Suite
+	path string
+}
+
+func TestFileMailer(t *testing.T) {
+	suite.Run(t, new(FileMailerTest))
+}
+
+func (t *FileMailerTest) SetupTest() {
+	t.path = filepath.Join(t.T().TempDir(), "mail.jsonl")
+}
+
+func (t *FileMailerTest) TestSendAppendsLines() {
+	m, err := NewMailer(t.path, false)
+	assert.Nil(t.T(), err)
+
+	assert.Nil(t.T(), m.Send(&dto.Mail{To: "a@example.com", Subject: "first", Body: "hello\nworld"}))
+	assert.Nil(t.T(), m.Send(&dto.Mail{To: "b@example.com", Subject: "second", Body: "bye"}))
+
+	f, err := os.Open(t.path)
+	assert.Nil(t.T(), err)
+	defer f.Close()
+
+	var records []record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := record{}
+		assert.Nil(t.T(), json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+
+	assert.Len(t.T(), records, 2)
+	assert.Equal(t.T(), dto.Mail{To: "a@example.com", Subject: "first", Body: "hello\nworld"}, records[0].Mail)
+	assert.Equal(t.T(), "b@example.com", records[1].To)
+	assert.False(t.T(), records[1].SentAt.IsZero())
+}
+
+func (t *FileMailerTest) TestSendWithoutPath() {
+	m, err := NewMailer("", true)
+	assert.Nil(t.T(), err)
+
+	assert.Nil(t.T(), m.Send(&dto.Mail{To: "a@example.com", Subject: "subject", Body: "body"}))
+}
+
+func (t *FileMailerTest) TestNewMailerWithoutPathOutsideDebug() {
+	_, err := NewMailer("", false)
+
+	assert.NotNil(t.T(), err)
+}
@@ -0,0 +1,54 @@
+package smtp
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+type mailerImpl struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewMailer(conf cfgldr.Mail) (*mailerImpl, error) {
+	if conf.Host == "" || conf.From == "" {
+		return nil, errors.New("missing host or from of the smtp mailer")
+	}
+
+	m := &mailerImpl{
+		addr: net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)),
+		from: conf.From,
+	}
+
+	// net/smtp upgrades the connection with STARTTLS when the server supports it and refuses plain auth without TLS
+	if conf.Username != "" {
+		m.auth = smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
+	}
+
+	return m, nil
+}
+
+func (m *mailerImpl) Send(mail *dto.Mail) error {
+	if strings.ContainsAny(mail.To, "\r\n") {
+		return errors.New("invalid recipient")
+	}
+
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.from,
+		mail.To,
+		mime.QEncoding.Encode("UTF-8", mail.Subject),
+		mail.Body,
+	)
+
+	return errors.Wrap(smtp.SendMail(m.addr, m.auth, m.from, []string{mail.To}, []byte(msg)), "error occurs while sending the mail")
+}
@@ -0,0 +1,54 @@
+package chula_sso
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/client/chula_sso"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type providerImpl struct {
+	client chula_sso.ChulaSSO
+}
+
+func NewProvider(client chula_sso.ChulaSSO) *providerImpl {
+	return &providerImpl{client: client}
+}
+
+func (p *providerImpl) Name() auth.Provider {
+	return auth.CHULA_SSO
+}
+
+func (p *providerImpl) Resolve(ticket string, _ *dto.LoginState) (*dto.Identity, error) {
+	if ticket == "" {
+		return nil, status.Error(codes.InvalidArgument, "No ticket is provided")
+	}
+
+	ssoData := dto.ChulaSSOCredential{}
+
+	err := p.client.VerifyTicket(ticket, &ssoData)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth service").
+			Str("module", "verify ticket").
+			Msgf("Someone is trying to logging in using SSO ticket")
+		return nil, err
+	}
+
+	subject := ssoData.UID
+	if subject == "" {
+		subject = ssoData.Ouid
+	}
+
+	return &dto.Identity{
+		Subject:   subject,
+		StudentID: ssoData.Ouid,
+		Firstname: ssoData.Firstname,
+		Lastname:  ssoData.Lastname,
+		Email:     ssoData.Email,
+		Provider:  auth.CHULA_SSO,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/mailer"
+	magic_link_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/magic_link"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type providerImpl struct {
+	magicLinkService magic_link_svc.Service
+	mailer           mailer.Mailer
+	url              *url.URL
+}
+
+func NewProvider(magicLinkService magic_link_svc.Service, mailer mailer.Mailer, conf cfgldr.MagicLink) (*providerImpl, error) {
+	u, err := url.Parse(conf.Url)
+	if err != nil || !u.IsAbs() {
+		return nil, errors.New(fmt.Sprintf("invalid magic link url %q", conf.Url))
+	}
+
+	return &providerImpl{
+		magicLinkService: magicLinkService,
+		mailer:           mailer,
+		url:              u,
+	}, nil
+}
+
+func (p *providerImpl) Name() auth.Provider {
+	return auth.EMAIL
+}
+
+// SendLink mails the login link to anyone, only a student email or an email linked by an admin can login with it
+func (p *providerImpl) SendLink(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" {
+		return status.Error(codes.InvalidArgument, "Invalid email")
+	}
+
+	email = strings.ToLower(address.Address)
+
+	token, err := p.magicLinkService.Create(email)
+	if err != nil {
+		if err == magic_link_svc.ErrTooManyRequests {
+			return status.Error(codes.ResourceExhausted, "Please wait before requesting another link")
+		}
+
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "email").
+			Msg("Error creating the magic link")
+		return status.Error(codes.Internal, "Internal service error")
+	}
+
+	err = p.mailer.Send(&dto.Mail{
+		To:      email,
+		Subject: "Login to RPKM66",
+		Body:    fmt.Sprintf("Open the link below to login to RPKM66, the link can only be used once.\n\n%s\n\nIf you did not request this, you can ignore this email.", p.link(token)),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "email").
+			Msg("Error sending the magic link")
+		return status.Error(codes.Unavailable, "Cannot send the email")
+	}
+
+	return nil
+}
+
+func (p *providerImpl) Resolve(token string, _ *dto.LoginState) (*dto.Identity, error) {
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "No token is provided")
+	}
+
+	link, err := p.magicLinkService.Consume(token)
+	if err != nil {
+		if err == magic_link_svc.ErrInvalidToken {
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "email").
+			Msg("Error reading the magic link")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	ouid, err := utils.GetOuidFromGmail(link.Email)
+	if err != nil {
+		ouid = ""
+	}
+
+	return &dto.Identity{
+		Subject:       link.Email,
+		StudentID:     ouid,
+		Email:         link.Email,
+		EmailVerified: true,
+		Provider:      auth.EMAIL,
+	}, nil
+}
+
+func (p *providerImpl) link(token string) string {
+	u := *p.url
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,182 @@
+package email
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/auth"
+	magic_link_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/magic_link"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type EmailProviderTest struct {
+	suite.Suite
+	conf cfgldr.MagicLink
+}
+
+func TestEmailProvider(t *testing.T) {
+	suite.Run(t, new(EmailProviderTest))
+}
+
+func (t *EmailProviderTest) SetupTest() {
+	t.conf = cfgldr.MagicLink{Url: "https://rabnongkaomai.com/login/email?lang=th"}
+}
+
+func (t *EmailProviderTest) TestSendLinkSuccess() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Create", "john.d@alumni.chula.ac.th").Return("token", nil)
+
+	var sent *dto.Mail
+
+	mailer := &mock.MailerMock{}
+	mailer.On("Send", testify.Anything).Run(func(args testify.Arguments) {
+		sent = args.Get(0).(*dto.Mail)
+	}).Return(nil)
+
+	p, err := NewProvider(magicLinkService, mailer, t.conf)
+	assert.Nil(t.T(), err)
+
+	err = p.SendLink("John.D@Alumni.Chula.ac.th")
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "john.d@alumni.chula.ac.th", sent.To)
+
+	var link *url.URL
+	for _, line := range strings.Split(sent.Body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link, _ = url.Parse(line)
+		}
+	}
+
+	assert.NotNil(t.T(), link)
+	assert.Equal(t.T(), "token", link.Query().Get("token"))
+	assert.Equal(t.T(), "th", link.Query().Get("lang"))
+}
+
+func (t *EmailProviderTest) TestSendLinkInvalidEmail() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+
+	p, err := NewProvider(magicLinkService, &mock.MailerMock{}, t.conf)
+	assert.Nil(t.T(), err)
+
+	err = p.SendLink("John <john.d@alumni.chula.ac.th>")
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	magicLinkService.AssertNotCalled(t.T(), "Create", testify.Anything)
+}
+
+func (t *EmailProviderTest) TestSendLinkCooldown() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Create", "john.d@alumni.chula.ac.th").Return("", magic_link_svc.ErrTooManyRequests)
+
+	mailer := &mock.MailerMock{}
+
+	p, err := NewProvider(magicLinkService, mailer, t.conf)
+	assert.Nil(t.T(), err)
+
+	err = p.SendLink("john.d@alumni.chula.ac.th")
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.ResourceExhausted, st.Code())
+	mailer.AssertNotCalled(t.T(), "Send", testify.Anything)
+}
+
+func (t *EmailProviderTest) TestSendLinkMailerErr() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Create", "john.d@alumni.chula.ac.th").Return("token", nil)
+
+	mailer := &mock.MailerMock{}
+	mailer.On("Send", testify.Anything).Return(errors.New("connection refused"))
+
+	p, err := NewProvider(magicLinkService, mailer, t.conf)
+	assert.Nil(t.T(), err)
+
+	err = p.SendLink("john.d@alumni.chula.ac.th")
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.Unavailable, st.Code())
+}
+
+func (t *EmailProviderTest) TestResolveStudent() {
+	want := &dto.Identity{
+		Subject:       "6430000021@student.chula.ac.th",
+		StudentID:     "6430000021",
+		Email:         "6430000021@student.chula.ac.th",
+		EmailVerified: true,
+		Provider:      auth.EMAIL,
+	}
+
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", "token").Return(&dto.MagicLink{Email: "6430000021@student.chula.ac.th"}, nil)
+
+	p, err := NewProvider(magicLinkService, &mock.MailerMock{}, t.conf)
+	assert.Nil(t.T(), err)
+
+	actual, err := p.Resolve("token", nil)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *EmailProviderTest) TestResolveNotStudent() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", "token").Return(&dto.MagicLink{Email: "john.d@alumni.chula.ac.th"}, nil)
+
+	p, err := NewProvider(magicLinkService, &mock.MailerMock{}, t.conf)
+	assert.Nil(t.T(), err)
+
+	actual, err := p.Resolve("token", nil)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "john.d@alumni.chula.ac.th", actual.Subject)
+	assert.Equal(t.T(), "", actual.StudentID)
+}
+
+func (t *EmailProviderTest) TestResolveLookAlikeDomain() {
+	for _, email := range []string{"6512345678@student-chula-ac-th.com", "6512345678@student.chula.ac.th.evil.com"} {
+		magicLinkService := &mock.MagicLinkServiceMock{}
+		magicLinkService.On("Consume", "token").Return(&dto.MagicLink{Email: email}, nil)
+
+		p, err := NewProvider(magicLinkService, &mock.MailerMock{}, t.conf)
+		assert.Nil(t.T(), err)
+
+		actual, err := p.Resolve("token", nil)
+
+		assert.Nil(t.T(), err)
+		assert.Equal(t.T(), "", actual.StudentID, email)
+	}
+}
+
+func (t *EmailProviderTest) TestResolveInvalidToken() {
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", "token").Return(nil, magic_link_svc.ErrInvalidToken)
+
+	p, err := NewProvider(magicLinkService, &mock.MailerMock{}, t.conf)
+	assert.Nil(t.T(), err)
+
+	actual, err := p.Resolve("token", nil)
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *EmailProviderTest) TestNewProviderInvalidUrl() {
+	_, err := NewProvider(&mock.MagicLinkServiceMock{}, &mock.MailerMock{}, cfgldr.MagicLink{Url: "/login/email"})
+
+	assert.NotNil(t.T(), err)
+}
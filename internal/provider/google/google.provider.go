@@ -0,0 +1,72 @@
+package google
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/client"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	google_oauth "github.com/isd-sgcu/rpkm66-auth/pkg/client/google_oauth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type providerImpl struct {
+	client google_oauth.GoogleOauthClient
+}
+
+func NewProvider(client google_oauth.GoogleOauthClient) *providerImpl {
+	return &providerImpl{client: client}
+}
+
+func (p *providerImpl) Name() auth.Provider {
+	return auth.GOOGLE
+}
+
+func (p *providerImpl) LoginUrl(state *dto.LoginState) string {
+	return p.client.AuthCodeURL(state.State, state.Nonce, utils.CodeChallenge(state.CodeVerifier))
+}
+
+func (p *providerImpl) Resolve(code string, state *dto.LoginState) (*dto.Identity, error) {
+	if code == "" {
+		return nil, status.Error(codes.InvalidArgument, "No code is provided")
+	}
+
+	if state == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid state")
+	}
+
+	response, err := p.client.GetUserEmail(code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		switch err {
+		case client.InvalidCode:
+			return nil, status.Error(codes.InvalidArgument, "Invalid code")
+		case client.InvalidIdToken:
+			return nil, status.Error(codes.Unauthenticated, "Invalid id token")
+		case client.ForbiddenDomain:
+			return nil, status.Error(codes.PermissionDenied, "Only chula student can login")
+		default:
+			log.Error().
+				Err(err).
+				Str("service", "auth").
+				Str("module", "google").
+				Msg("Unable to get user info")
+			return nil, status.Error(codes.Internal, "Internal server error")
+		}
+	}
+
+	ouid, err := utils.GetOuidFromGmail(response.Email)
+	if err != nil {
+		ouid = ""
+	}
+
+	return &dto.Identity{
+		Subject:       response.Subject,
+		StudentID:     ouid,
+		Firstname:     response.Firstname,
+		Lastname:      response.Lastname,
+		Email:         response.Email,
+		EmailVerified: response.EmailVerified,
+		Provider:      auth.GOOGLE,
+	}, nil
+}
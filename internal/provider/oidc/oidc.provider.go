@@ -0,0 +1,159 @@
+package oidc
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/client"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	oidc_client "github.com/isd-sgcu/rpkm66-auth/pkg/client/oidc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type providerImpl struct {
+	name             auth.Provider
+	client           oidc_client.OidcClient
+	trustStudentID   bool
+	studentIDClaim   string
+	studentIDPattern *regexp.Regexp
+	firstnameClaim   string
+	lastnameClaim    string
+	emailClaim       string
+}
+
+func NewProvider(client oidc_client.OidcClient, conf cfgldr.OidcProvider) (*providerImpl, error) {
+	name := auth.Provider(conf.Name)
+	if name == "" || name == auth.CHULA_SSO || name == auth.GOOGLE || name == auth.EMAIL {
+		return nil, errors.New(fmt.Sprintf("invalid oidc provider name %q", conf.Name))
+	}
+
+	if conf.TrustStudentID && conf.StudentIDClaim == "" {
+		return nil, errors.New(fmt.Sprintf("missing student_id_claim of the oidc provider %v", conf.Name))
+	}
+
+	p := &providerImpl{
+		name:           name,
+		client:         client,
+		trustStudentID: conf.TrustStudentID,
+		studentIDClaim: conf.StudentIDClaim,
+		firstnameClaim: withDefault(conf.FirstnameClaim, "given_name"),
+		lastnameClaim:  withDefault(conf.LastnameClaim, "family_name"),
+		emailClaim:     withDefault(conf.EmailClaim, "email"),
+	}
+
+	if conf.StudentIDPattern != "" {
+		pattern, err := regexp.Compile(conf.StudentIDPattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid student_id_pattern of the oidc provider %v", conf.Name)
+		}
+
+		p.studentIDPattern = pattern
+	}
+
+	return p, nil
+}
+
+func (p *providerImpl) Name() auth.Provider {
+	return p.name
+}
+
+func (p *providerImpl) LoginUrl(state *dto.LoginState) string {
+	return p.client.AuthCodeURL(state.State, state.Nonce, utils.CodeChallenge(state.CodeVerifier))
+}
+
+func (p *providerImpl) Resolve(code string, state *dto.LoginState) (*dto.Identity, error) {
+	if code == "" {
+		return nil, status.Error(codes.InvalidArgument, "No code is provided")
+	}
+
+	if state == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid state")
+	}
+
+	claims, err := p.client.GetClaims(code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		switch err {
+		case client.InvalidCode:
+			return nil, status.Error(codes.InvalidArgument, "Invalid code")
+		case client.InvalidIdToken:
+			return nil, status.Error(codes.Unauthenticated, "Invalid id token")
+		default:
+			log.Error().
+				Err(err).
+				Str("service", "auth").
+				Str("module", "oidc").
+				Str("provider", string(p.name)).
+				Msg("Unable to get user info")
+			return nil, status.Error(codes.Internal, "Internal server error")
+		}
+	}
+
+	studentID, _ := p.studentID(claims)
+
+	return &dto.Identity{
+		Subject:       stringClaim(claims, "sub"),
+		StudentID:     studentID,
+		Firstname:     stringClaim(claims, p.firstnameClaim),
+		Lastname:      stringClaim(claims, p.lastnameClaim),
+		Email:         stringClaim(claims, p.emailClaim),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Provider:      p.name,
+	}, nil
+}
+
+// studentID is only read from a provider marked trust_student_id, the claim of any other provider may be set by its users so their identity needs a link
+func (p *providerImpl) studentID(claims map[string]interface{}) (string, bool) {
+	if !p.trustStudentID {
+		return "", false
+	}
+
+	value := stringClaim(claims, p.studentIDClaim)
+	if value == "" {
+		return "", false
+	}
+
+	if p.studentIDPattern == nil {
+		return value, true
+	}
+
+	found := p.studentIDPattern.FindStringSubmatch(value)
+	switch {
+	case found == nil:
+		return "", false
+	case len(found) > 1:
+		return found[1], found[1] != ""
+	default:
+		return found[0], true
+	}
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim also accepts "true" as some providers send email_verified as a string
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+func withDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
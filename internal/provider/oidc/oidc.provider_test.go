@@ -0,0 +1,244 @@
+package oidc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/client"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type OidcProviderTest struct {
+	suite.Suite
+	server     *mock.Server
+	conf       cfgldr.OidcProvider
+	loginState *dto.LoginState
+}
+
+func TestOidcProvider(t *testing.T) {
+	suite.Run(t, new(OidcProviderTest))
+}
+
+func (t *OidcProviderTest) SetupTest() {
+	t.server = mock.NewServer(map[string]interface{}{
+		"sub":                "00000000-0000-0000-0000-000000000000",
+		"preferred_username": "6430000021@student.chula.ac.th",
+		"given_name":         "John",
+		"family_name":        "Doe",
+		"email":              "6430000021@student.chula.ac.th",
+		"email_verified":     true,
+	})
+
+	t.conf = cfgldr.OidcProvider{
+		Name:             "entra",
+		IssuerUrl:        t.server.URL,
+		ClientID:         t.server.ClientID,
+		ClientSecret:     t.server.ClientSecret,
+		RedirectUri:      "https://rabnongkaomai.com/callback",
+		TrustStudentID:   true,
+		StudentIDClaim:   "preferred_username",
+		StudentIDPattern: `^(\d{10})@student\.chula\.ac\.th$`,
+	}
+
+	t.loginState = &dto.LoginState{
+		State:        "state",
+		Nonce:        "nonce",
+		CodeVerifier: t.server.CodeVerifier,
+		Provider:     "entra",
+	}
+}
+
+func (t *OidcProviderTest) TearDownTest() {
+	t.server.Close()
+}
+
+func (t *OidcProviderTest) newProvider() *providerImpl {
+	c, err := client.NewOidcClient(t.conf, t.server.Client())
+	assert.Nil(t.T(), err)
+
+	p, err := NewProvider(c, t.conf)
+	assert.Nil(t.T(), err)
+
+	return p
+}
+
+func (t *OidcProviderTest) TestLoginUrl() {
+	p := t.newProvider()
+
+	loginUrl, err := url.Parse(p.LoginUrl(t.loginState))
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.server.URL+"/authorize", loginUrl.Scheme+"://"+loginUrl.Host+loginUrl.Path)
+	assert.Equal(t.T(), t.server.ClientID, loginUrl.Query().Get("client_id"))
+	assert.Equal(t.T(), t.conf.RedirectUri, loginUrl.Query().Get("redirect_uri"))
+	assert.Equal(t.T(), "openid profile email", loginUrl.Query().Get("scope"))
+	assert.Equal(t.T(), "state", loginUrl.Query().Get("state"))
+	assert.Equal(t.T(), utils.CodeChallenge(t.server.CodeVerifier), loginUrl.Query().Get("code_challenge"))
+}
+
+func (t *OidcProviderTest) TestResolveSuccess() {
+	want := &dto.Identity{
+		Subject:       "00000000-0000-0000-0000-000000000000",
+		StudentID:     "6430000021",
+		Firstname:     "John",
+		Lastname:      "Doe",
+		Email:         "6430000021@student.chula.ac.th",
+		EmailVerified: true,
+		Provider:      auth.Provider("entra"),
+	}
+
+	p := t.newProvider()
+
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *OidcProviderTest) TestResolveInvalidCode() {
+	p := t.newProvider()
+
+	actual, err := p.Resolve("invalid-code", t.loginState)
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *OidcProviderTest) TestResolveInvalidCodeVerifier() {
+	p := t.newProvider()
+
+	t.loginState.CodeVerifier = "another-code-verifier"
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *OidcProviderTest) TestResolveMissingState() {
+	p := t.newProvider()
+
+	actual, err := p.Resolve(t.server.Code, nil)
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *OidcProviderTest) TestResolveNotStudent() {
+	t.server.Claims["preferred_username"] = "john.d@chula.ac.th"
+
+	p := t.newProvider()
+
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "00000000-0000-0000-0000-000000000000", actual.Subject)
+	assert.Equal(t.T(), "", actual.StudentID)
+}
+
+func (t *OidcProviderTest) TestResolveUntrustedStudentID() {
+	t.conf.TrustStudentID = false
+
+	p := t.newProvider()
+
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), "00000000-0000-0000-0000-000000000000", actual.Subject)
+	assert.Equal(t.T(), "", actual.StudentID)
+}
+
+func (t *OidcProviderTest) TestResolveEmailVerified() {
+	p := t.newProvider()
+
+	t.server.Claims["email_verified"] = "true"
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), actual.EmailVerified)
+
+	delete(t.server.Claims, "email_verified")
+	actual, err = p.Resolve(t.server.Code, t.loginState)
+
+	assert.Nil(t.T(), err)
+	assert.False(t.T(), actual.EmailVerified)
+}
+
+func (t *OidcProviderTest) TestResolveMissingSubject() {
+	delete(t.server.Claims, "sub")
+
+	t.assertInvalidIdToken()
+}
+
+func (t *OidcProviderTest) TestResolveNonceMismatch() {
+	t.loginState.Nonce = "another-nonce"
+
+	t.assertInvalidIdToken()
+}
+
+func (t *OidcProviderTest) TestResolveAudienceMismatch() {
+	t.server.IdClaims = jwt.MapClaims{"aud": "another-client-id"}
+
+	t.assertInvalidIdToken()
+}
+
+func (t *OidcProviderTest) TestResolveIssuerMismatch() {
+	t.server.IdClaims = jwt.MapClaims{"iss": "https://issuer.example.com"}
+
+	t.assertInvalidIdToken()
+}
+
+func (t *OidcProviderTest) TestResolveUserinfoSubjectMismatch() {
+	t.server.IdClaims = jwt.MapClaims{"sub": "11111111-1111-1111-1111-111111111111"}
+
+	t.assertInvalidIdToken()
+}
+
+func (t *OidcProviderTest) assertInvalidIdToken() {
+	p := t.newProvider()
+
+	actual, err := p.Resolve(t.server.Code, t.loginState)
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *OidcProviderTest) TestNewOidcClientIssuerMismatch() {
+	t.conf.IssuerUrl = t.server.URL + "/tenant"
+
+	_, err := client.NewOidcClient(t.conf, t.server.Client())
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *OidcProviderTest) TestNewProviderReservedName() {
+	t.conf.Name = string(auth.GOOGLE)
+
+	_, err := NewProvider(nil, t.conf)
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *OidcProviderTest) TestNewProviderMissingStudentIDClaim() {
+	t.conf.StudentIDClaim = ""
+
+	_, err := NewProvider(nil, t.conf)
+
+	assert.NotNil(t.T(), err)
+}
@@ -0,0 +1,40 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+// publisherImpl appends every event as a json line to the file
+type publisherImpl struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewPublisher(path string) *publisherImpl {
+	return &publisherImpl{path: path}
+}
+
+func (p *publisherImpl) Publish(_ context.Context, event *dto.AuditMessage) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error occurs while opening the audit file")
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return errors.Wrap(err, "error occurs while writing the audit file")
+}
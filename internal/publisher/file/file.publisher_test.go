@@ -0,0 +1,58 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilePublisherTest struct {
+	suite.Suite
+	path string
+}
+
+func TestFilePublisher(t *testing.T) {
+	suite.Run(t, new(FilePublisherTest))
+}
+
+func (t *FilePublisherTest) SetupTest() {
+	t.path = filepath.Join(t.T().TempDir(), "audit.jsonl")
+}
+
+func (t *FilePublisherTest) TestPublishAppendsLines() {
+	p := NewPublisher(t.path)
+
+	first := &dto.AuditMessage{ID: "first", Event: "login_success", UserID: "user-id", Timestamp: time.Now().UTC().Truncate(time.Second)}
+	second := &dto.AuditMessage{ID: "second", Event: "logout", UserID: "user-id", Timestamp: time.Now().UTC().Truncate(time.Second)}
+
+	assert.Nil(t.T(), p.Publish(context.Background(), first))
+	assert.Nil(t.T(), p.Publish(context.Background(), second))
+
+	f, err := os.Open(t.path)
+	assert.Nil(t.T(), err)
+	defer f.Close()
+
+	var events []*dto.AuditMessage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := &dto.AuditMessage{}
+		assert.Nil(t.T(), json.Unmarshal(scanner.Bytes(), event))
+		events = append(events, event)
+	}
+
+	assert.Equal(t.T(), []*dto.AuditMessage{first, second}, events)
+}
+
+func (t *FilePublisherTest) TestPublishUnwritablePath() {
+	p := NewPublisher(filepath.Join(t.path, "missing", "audit.jsonl"))
+
+	assert.NotNil(t.T(), p.Publish(context.Background(), &dto.AuditMessage{Event: "logout"}))
+}
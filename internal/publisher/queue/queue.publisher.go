@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultSize       = 1000
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	publishTimeout    = 10 * time.Second
+)
+
+var (
+	ErrQueueFull = errors.New("audit queue is full")
+	ErrClosed    = errors.New("audit queue is closed")
+)
+
+// publisherImpl puts the events in a bounded queue that a single worker delivers to the sink,
+// an event is dropped instead of waiting when the queue is full so the caller is never blocked by the sink
+type publisherImpl struct {
+	publisher  publisher.EventPublisher
+	events     chan *dto.AuditMessage
+	maxRetries int
+	backoff    time.Duration
+	mu         sync.RWMutex
+	closed     bool
+	done       chan struct{}
+}
+
+func NewPublisher(publisher publisher.EventPublisher, size int, maxRetries int) *publisherImpl {
+	return newPublisher(publisher, size, maxRetries, defaultBackoff)
+}
+
+func newPublisher(publisher publisher.EventPublisher, size int, maxRetries int, backoff time.Duration) *publisherImpl {
+	if size <= 0 {
+		size = defaultSize
+	}
+
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	p := &publisherImpl{
+		publisher:  publisher,
+		events:     make(chan *dto.AuditMessage, size),
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		done:       make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+// Publish only enqueues the event, the context of the caller is not used because the request is over before the event is delivered
+func (p *publisherImpl) Publish(_ context.Context, event *dto.AuditMessage) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.events <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are delivered or the context is done
+func (p *publisherImpl) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *publisherImpl) run() {
+	defer close(p.done)
+
+	for event := range p.events {
+		p.deliver(event)
+	}
+}
+
+func (p *publisherImpl) deliver(event *dto.AuditMessage) {
+	backoff := p.backoff
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		err := p.publisher.Publish(ctx, event)
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		if attempt >= p.maxRetries {
+			log.Error().
+				Err(err).
+				Str("service", "auth").
+				Str("module", "audit publisher").
+				Str("event", event.Event).
+				Str("event_id", event.ID).
+				Int("attempts", attempt+1).
+				Msg("Dropping the audit event after retrying")
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/publisher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type QueuePublisherTest struct {
+	suite.Suite
+	Event *dto.AuditMessage
+}
+
+func TestQueuePublisher(t *testing.T) {
+	suite.Run(t, new(QueuePublisherTest))
+}
+
+func (t *QueuePublisherTest) SetupTest() {
+	t.Event = &dto.AuditMessage{
+		ID:        "event-id",
+		Event:     "login_success",
+		UserID:    "user-id",
+		Timestamp: time.Now(),
+	}
+}
+
+func (t *QueuePublisherTest) close(p *publisherImpl) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.Nil(t.T(), p.Close(ctx))
+}
+
+func (t *QueuePublisherTest) TestPublishDelivers() {
+	sink := &mock.InMemoryPublisher{}
+	p := newPublisher(sink, 10, 3, time.Millisecond)
+
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+	t.close(p)
+
+	assert.Equal(t.T(), []*dto.AuditMessage{t.Event}, sink.Events())
+}
+
+func (t *QueuePublisherTest) TestPublishRetries() {
+	sink := &mock.InMemoryPublisher{Failures: 2}
+	p := newPublisher(sink, 10, 3, time.Millisecond)
+
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+	t.close(p)
+
+	assert.Equal(t.T(), 3, sink.Calls())
+	assert.Equal(t.T(), []*dto.AuditMessage{t.Event}, sink.Events())
+}
+
+func (t *QueuePublisherTest) TestPublishDropsAfterRetries() {
+	sink := &mock.InMemoryPublisher{Failures: 10}
+	p := newPublisher(sink, 10, 2, time.Millisecond)
+
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+	t.close(p)
+
+	assert.Equal(t.T(), 3, sink.Calls())
+	assert.Empty(t.T(), sink.Events())
+}
+
+func (t *QueuePublisherTest) TestPublishFullQueueDoesNotBlock() {
+	block := make(chan struct{})
+	sink := &mock.InMemoryPublisher{Block: block}
+	p := newPublisher(sink, 1, 1, time.Millisecond)
+
+	// the worker holds the first event and the second one fills the queue
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+	assert.Eventually(t.T(), func() bool { return len(p.events) == 0 }, time.Second, time.Millisecond)
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+
+	done := make(chan error)
+	go func() {
+		done <- p.Publish(context.Background(), t.Event)
+	}()
+
+	select {
+	case err := <-done:
+		assert.Equal(t.T(), ErrQueueFull, err)
+	case <-time.After(time.Second):
+		t.T().Fatal("Publish is blocked by the sink")
+	}
+
+	close(block)
+	t.close(p)
+
+	assert.Len(t.T(), sink.Events(), 2)
+}
+
+func (t *QueuePublisherTest) TestPublishAfterClose() {
+	p := newPublisher(&mock.InMemoryPublisher{}, 10, 1, time.Millisecond)
+	t.close(p)
+
+	assert.Equal(t.T(), ErrClosed, p.Publish(context.Background(), t.Event))
+	t.close(p)
+}
+
+func (t *QueuePublisherTest) TestCloseDeadline() {
+	block := make(chan struct{})
+	defer close(block)
+
+	p := newPublisher(&mock.InMemoryPublisher{Block: block}, 10, 1, time.Millisecond)
+	assert.Nil(t.T(), p.Publish(context.Background(), t.Event))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t.T(), context.DeadlineExceeded, p.Close(ctx))
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+)
+
+const (
+	defaultStream = "rpkm66:auth:audit"
+	defaultMaxLen = 100000
+)
+
+// publisherImpl adds every event to a redis stream, the stream is trimmed to about max len entries
+type publisherImpl struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewPublisher(client *redis.Client, stream string, maxLen int64) *publisherImpl {
+	if stream == "" {
+		stream = defaultStream
+	}
+
+	if maxLen <= 0 {
+		maxLen = defaultMaxLen
+	}
+
+	return &publisherImpl{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (p *publisherImpl) Publish(ctx context.Context, event *dto.AuditMessage) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event":   event.Event,
+			"payload": payload,
+		},
+	}).Err()
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	TimestampHeader = "X-Rpkm66-Timestamp"
+	SignatureHeader = "X-Rpkm66-Signature"
+)
+
+// publisherImpl posts every event as json, the receiver checks the signature over the timestamp and the body to reject forged or replayed events
+type publisherImpl struct {
+	client *http.Client
+	url    string
+	secret []byte
+}
+
+func NewPublisher(webhookUrl string, secret string) (*publisherImpl, error) {
+	u, err := url.Parse(webhookUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("webhook url must be an absolute http url")
+	}
+
+	if secret == "" {
+		return nil, errors.New("webhook secret is required")
+	}
+
+	return &publisherImpl{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    webhookUrl,
+		secret: []byte(secret),
+	}, nil
+}
+
+func (p *publisherImpl) Publish(ctx context.Context, event *dto.AuditMessage) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(p.secret, timestamp, body))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error occurs while calling the webhook")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
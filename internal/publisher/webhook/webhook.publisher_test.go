@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookPublisherTest struct {
+	suite.Suite
+	Secret string
+	Event  *dto.AuditMessage
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	suite.Run(t, new(WebhookPublisherTest))
+}
+
+func (t *WebhookPublisherTest) SetupTest() {
+	t.Secret = "webhook-secret"
+	t.Event = &dto.AuditMessage{
+		ID:        "event-id",
+		Event:     "login_failure",
+		StudentID: "6530000021",
+		Detail:    "Only chula student can login",
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func (t *WebhookPublisherTest) TestPublishSigned() {
+	var received *dto.AuditMessage
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		assert.Equal(t.T(), http.MethodPost, r.Method)
+		assert.Equal(t.T(), "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t.T(), "sha256="+Sign([]byte(t.Secret), r.Header.Get(TimestampHeader), body), r.Header.Get(SignatureHeader))
+
+		received = &dto.AuditMessage{}
+		assert.Nil(t.T(), json.Unmarshal(body, received))
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	p, err := NewPublisher(server.URL, t.Secret)
+	assert.Nil(t.T(), err)
+
+	err = p.Publish(context.Background(), t.Event)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Event, received)
+}
+
+func (t *WebhookPublisherTest) TestPublishErrorStatus() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	p, err := NewPublisher(server.URL, t.Secret)
+	assert.Nil(t.T(), err)
+
+	err = p.Publish(context.Background(), t.Event)
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *WebhookPublisherTest) TestSignDependsOnTimestamp() {
+	body := []byte(`{"event":"logout"}`)
+
+	assert.NotEqual(t.T(), Sign([]byte(t.Secret), "1700000000", body), Sign([]byte(t.Secret), "1700000001", body))
+	assert.NotEqual(t.T(), Sign([]byte(t.Secret), "1700000000", body), Sign([]byte("other"), "1700000000", body))
+}
+
+func (t *WebhookPublisherTest) TestNewPublisherInvalidConfig() {
+	_, err := NewPublisher("/audit", t.Secret)
+	assert.NotNil(t.T(), err)
+
+	_, err = NewPublisher("ftp://example.com/audit", t.Secret)
+	assert.NotNil(t.T(), err)
+
+	_, err = NewPublisher("https://example.com/audit", "")
+	assert.NotNil(t.T(), err)
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(in *entity.AuditEvent) error {
+	return r.db.Create(&in).Error
+}
+
+// Find returns the newest events first, the total counts every event that matches the filter
+func (r *Repository) Find(filter *dto.AuditFilter, result *[]*entity.AuditEvent, total *int64) error {
+	query := r.db.Model(&entity.AuditEvent{})
+
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.StudentID != "" {
+		query = query.Where("student_id = ?", filter.StudentID)
+	}
+
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	err := query.Count(total).Error
+	if err != nil {
+		return err
+	}
+
+	return query.
+		Order("created_at desc").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(result).Error
+}
@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"time"
+
 	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -17,8 +20,8 @@ func (r *Repository) FindByUserID(uid string, result *entity.Auth) error {
 	return r.db.First(&result, "user_id = ?", uid).Error
 }
 
-func (r *Repository) FindByRefreshToken(refreshToken string, result *entity.Auth) error {
-	return r.db.First(&result, "refresh_token = ?", refreshToken).Error
+func (r *Repository) FindByID(id string, result *entity.Auth) error {
+	return r.db.First(&result, "id = ?", id).Error
 }
 
 func (r *Repository) Create(auth *entity.Auth) error {
@@ -26,5 +29,94 @@ func (r *Repository) Create(auth *entity.Auth) error {
 }
 
 func (r *Repository) Update(id string, auth *entity.Auth) error {
-	return r.db.Where(id, "id = ?", id).Updates(&auth).First(&auth, "id = ?", id).Error
+	return r.db.Where("id = ?", id).Updates(&auth).First(&auth, "id = ?", id).Error
+}
+
+func (r *Repository) FindSessionByID(id string, result *entity.Session) error {
+	return r.db.First(&result, "id = ?", id).Error
+}
+
+func (r *Repository) FindSessionsByUserID(uid string, result *[]*entity.Session) error {
+	return r.db.Order("created_at asc").Find(&result, "user_id = ?", uid).Error
+}
+
+func (r *Repository) CreateSession(session *entity.Session) error {
+	return r.db.Create(&session).Error
+}
+
+func (r *Repository) UpdateSession(id string, session *entity.Session) error {
+	return r.db.Where("id = ?", id).Updates(&session).First(&session, "id = ?", id).Error
+}
+
+func (r *Repository) DeleteSession(id string) error {
+	return r.db.Delete(&entity.Session{}, "id = ?", id).Error
+}
+
+func (r *Repository) FindRefreshToken(token string, result *entity.RefreshToken) error {
+	return r.db.First(&result, "token = ?", token).Error
+}
+
+func (r *Repository) CreateRefreshToken(token *entity.RefreshToken) error {
+	return r.db.Create(&token).Error
+}
+
+func (r *Repository) RotateRefreshToken(id string) error {
+	result := r.db.Model(&entity.RefreshToken{}).Where("id = ? AND rotated_at IS NULL", id).Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteRefreshTokensBySessionID(sessionId string) error {
+	return r.db.Delete(&entity.RefreshToken{}, "session_id = ?", sessionId).Error
+}
+
+func (r *Repository) FindRoleGrantByStudentID(sid string, result *entity.RoleGrant) error {
+	return r.db.First(&result, "student_id = ?", sid).Error
+}
+
+func (r *Repository) UpsertRoleGrants(grants *[]*entity.RoleGrant) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "granted_by", "updated_at"}),
+	}).Create(grants).Error
+}
+
+func (r *Repository) FindIdentity(provider string, subject string, result *entity.Identity) error {
+	return r.db.First(&result, "provider = ? AND subject = ?", provider, subject).Error
+}
+
+func (r *Repository) FindPendingIdentityByEmail(provider string, email string, result *entity.Identity) error {
+	return r.db.First(&result, "provider = ? AND subject = '' AND lower(email) = lower(?)", provider, email).Error
+}
+
+func (r *Repository) FindIdentitiesByAuthID(authId string, result *[]*entity.Identity) error {
+	return r.db.Order("created_at asc").Find(&result, "auth_id = ?", authId).Error
+}
+
+func (r *Repository) CreateIdentity(identity *entity.Identity) error {
+	return r.db.Create(&identity).Error
+}
+
+func (r *Repository) UpdateIdentity(id string, identity *entity.Identity) error {
+	return r.db.Where("id = ?", id).Updates(&identity).First(&identity, "id = ?", id).Error
+}
+
+// DeleteIdentity removes the row for good, a soft deleted row would still hold the unique provider and subject
+func (r *Repository) DeleteIdentity(id string) error {
+	return r.db.Unscoped().Delete(&entity.Identity{}, "id = ?", id).Error
+}
+
+func (r *Repository) FindServiceClient(clientId string, result *entity.ServiceClient) error {
+	return r.db.First(&result, "client_id = ?", clientId).Error
+}
+
+func (r *Repository) CreateServiceClient(client *entity.ServiceClient) error {
+	return r.db.Create(&client).Error
 }
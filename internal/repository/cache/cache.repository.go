@@ -38,3 +38,36 @@ func (r *Repository) GetCache(key string, value interface{}) (err error) {
 
 	return json.Unmarshal([]byte(v), value)
 }
+
+func (r *Repository) RemoveCache(key string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.client.Del(ctx, key).Err()
+}
+
+// PopCache reads and removes the key atomically, so the value can only be consumed once
+func (r *Repository) PopCache(key string, value interface{}) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	v, err := r.client.GetDel(ctx, key).Result()
+	if err != nil {
+		return
+	}
+
+	return json.Unmarshal([]byte(v), value)
+}
+
+// SaveCacheIfAbsent only sets the key when it does not exist, false is returned when it already does
+func (r *Repository) SaveCacheIfAbsent(key string, value interface{}, ttl int) (ok bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	v, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	return r.client.SetNX(ctx, key, v, time.Duration(ttl)*time.Second).Result()
+}
@@ -0,0 +1,26 @@
+package permission
+
+import (
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) FindAll(result *[]*entity.RolePermission) error {
+	return r.db.Order("role asc, permission asc").Find(result).Error
+}
+
+func (r *Repository) Count(result *int64) error {
+	return r.db.Model(&entity.RolePermission{}).Count(result).Error
+}
+
+func (r *Repository) CreateBatch(in *[]*entity.RolePermission) error {
+	return r.db.Create(in).Error
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+	audit_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/audit"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// serviceImpl streams the events to the publisher when there is one, the publisher must not block because Record is on the login path
+type serviceImpl struct {
+	repo       audit_repo.Repository
+	publisher  publisher.EventPublisher
+	clientInfo *utils.ClientInfo
+}
+
+func NewService(repo audit_repo.Repository, publisher publisher.EventPublisher, clientInfo *utils.ClientInfo) *serviceImpl {
+	return &serviceImpl{
+		repo:       repo,
+		publisher:  publisher,
+		clientInfo: clientInfo,
+	}
+}
+
+// Record stamps the client of the request onto the event, it never fails the caller so a lost event only shows up in the log
+func (s *serviceImpl) Record(ctx context.Context, event *entity.AuditEvent) {
+	event.ClientIP, event.UserAgent = s.clientInfo.Get(ctx)
+
+	err := s.repo.Create(event)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "audit").
+			Str("event", event.Event).
+			Str("user_id", event.UserID).
+			Str("student_id", event.StudentID).
+			Msg("Error recording the audit event")
+	}
+
+	if s.publisher == nil {
+		return
+	}
+
+	err = s.publisher.Publish(ctx, RawToAuditMessage(event))
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "audit").
+			Str("event", event.Event).
+			Str("user_id", event.UserID).
+			Str("student_id", event.StudentID).
+			Msg("Error publishing the audit event")
+	}
+}
+
+func (s *serviceImpl) Find(filter *dto.AuditFilter) ([]*entity.AuditEvent, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultPageSize
+	}
+
+	if filter.PageSize > maxPageSize {
+		filter.PageSize = maxPageSize
+	}
+
+	var events []*entity.AuditEvent
+	var total int64
+
+	err := s.repo.Find(filter, &events, &total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// RawToAuditMessage uses the current time when the event could not be stored and has no creation time
+func RawToAuditMessage(in *entity.AuditEvent) *dto.AuditMessage {
+	message := &dto.AuditMessage{
+		Event:     in.Event,
+		UserID:    in.UserID,
+		StudentID: in.StudentID,
+		Provider:  in.Provider,
+		ActorID:   in.ActorID,
+		Detail:    in.Detail,
+		ClientIP:  in.ClientIP,
+		UserAgent: in.UserAgent,
+		Timestamp: in.CreatedAt,
+	}
+
+	if in.ID != uuid.Nil {
+		message.ID = in.ID.String()
+	}
+
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+
+	return message
+}
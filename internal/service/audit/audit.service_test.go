@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	base "github.com/isd-sgcu/rpkm66-auth/internal/entity"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/audit"
+	publisher_mock "github.com/isd-sgcu/rpkm66-auth/mocks/publisher"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type AuditServiceTest struct {
+	suite.Suite
+	Events []*entity.AuditEvent
+}
+
+func TestAuditService(t *testing.T) {
+	suite.Run(t, new(AuditServiceTest))
+}
+
+func (t *AuditServiceTest) SetupTest() {
+	t.Events = []*entity.AuditEvent{
+		{Event: string(role.LOGIN_SUCCESS), UserID: "user-id", Provider: string(role.CHULA_SSO)},
+	}
+}
+
+func (t *AuditServiceTest) TestRecordStampsClient() {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 54321}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "203.0.113.7, 10.0.0.1", "user-agent", "rpkm66-web"))
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Create", testify.Anything).Return(nil)
+
+	clientInfo, _ := utils.NewClientInfo([]string{"10.0.0.0/8"})
+
+	srv := NewService(repo, nil, clientInfo)
+
+	srv.Record(ctx, &entity.AuditEvent{Event: string(role.LOGOUT), UserID: "user-id"})
+
+	repo.AssertCalled(t.T(), "Create", &entity.AuditEvent{
+		Event:     string(role.LOGOUT),
+		UserID:    "user-id",
+		ClientIP:  "203.0.113.7",
+		UserAgent: "rpkm66-web",
+	})
+}
+
+func (t *AuditServiceTest) TestRecordRepositoryErr() {
+	repo := &mock.RepositoryMock{}
+	repo.On("Create", testify.Anything).Return(errors.New("connection refused"))
+
+	srv := NewService(repo, nil, nil)
+
+	assert.NotPanics(t.T(), func() {
+		srv.Record(context.Background(), &entity.AuditEvent{Event: string(role.LOGOUT)})
+	})
+	repo.AssertNumberOfCalls(t.T(), "Create", 1)
+}
+
+func (t *AuditServiceTest) TestRecordPublishes() {
+	event := &entity.AuditEvent{Base: base.Base{ID: uuid.New(), CreatedAt: time.Now()}, Event: string(role.LOGIN_SUCCESS), UserID: "user-id", Provider: string(role.GOOGLE)}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Create", event).Return(nil)
+
+	publisher := &publisher_mock.InMemoryPublisher{}
+
+	srv := NewService(repo, publisher, nil)
+
+	srv.Record(context.Background(), event)
+
+	assert.Equal(t.T(), []*dto.AuditMessage{{
+		ID:        event.ID.String(),
+		Event:     string(role.LOGIN_SUCCESS),
+		UserID:    "user-id",
+		Provider:  string(role.GOOGLE),
+		Timestamp: event.CreatedAt,
+	}}, publisher.Events())
+}
+
+func (t *AuditServiceTest) TestRecordPublishesWhenStoreFails() {
+	repo := &mock.RepositoryMock{}
+	repo.On("Create", testify.Anything).Return(errors.New("connection refused"))
+
+	publisher := &publisher_mock.InMemoryPublisher{}
+
+	srv := NewService(repo, publisher, nil)
+
+	srv.Record(context.Background(), &entity.AuditEvent{Event: string(role.LOGIN_FAILURE), StudentID: "6530000021"})
+
+	events := publisher.Events()
+	assert.Len(t.T(), events, 1)
+	assert.Empty(t.T(), events[0].ID)
+	assert.Equal(t.T(), "6530000021", events[0].StudentID)
+	assert.WithinDuration(t.T(), time.Now(), events[0].Timestamp, time.Second)
+}
+
+func (t *AuditServiceTest) TestRecordPublisherErr() {
+	repo := &mock.RepositoryMock{}
+	repo.On("Create", testify.Anything).Return(nil)
+
+	srv := NewService(repo, &publisher_mock.InMemoryPublisher{Failures: 1}, nil)
+
+	assert.NotPanics(t.T(), func() {
+		srv.Record(context.Background(), &entity.AuditEvent{Event: string(role.LOGOUT)})
+	})
+	repo.AssertNumberOfCalls(t.T(), "Create", 1)
+}
+
+func (t *AuditServiceTest) TestFindDefaultPage() {
+	var events []*entity.AuditEvent
+	var total int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Find", &dto.AuditFilter{UserID: "user-id", Page: 1, PageSize: defaultPageSize}, &events, &total).Return(t.Events, int64(1), nil)
+
+	srv := NewService(repo, nil, nil)
+
+	actual, count, err := srv.Find(&dto.AuditFilter{UserID: "user-id"})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.Events, actual)
+	assert.Equal(t.T(), int64(1), count)
+}
+
+func (t *AuditServiceTest) TestFindCapPageSize() {
+	var events []*entity.AuditEvent
+	var total int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Find", &dto.AuditFilter{Page: 3, PageSize: maxPageSize}, &events, &total).Return(t.Events, int64(250), nil)
+
+	srv := NewService(repo, nil, nil)
+
+	_, count, err := srv.Find(&dto.AuditFilter{Page: 3, PageSize: 1000})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), int64(250), count)
+}
+
+func (t *AuditServiceTest) TestFindErr() {
+	var events []*entity.AuditEvent
+	var total int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Find", &dto.AuditFilter{Page: 1, PageSize: defaultPageSize}, &events, &total).Return(nil, int64(0), errors.New("connection refused"))
+
+	srv := NewService(repo, nil, nil)
+
+	actual, _, err := srv.Find(&dto.AuditFilter{})
+
+	assert.NotNil(t.T(), err)
+	assert.Nil(t.T(), actual)
+}
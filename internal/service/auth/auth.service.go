@@ -1,401 +1,1661 @@
 package auth
 
 import (
+	"bytes"
 	"context"
-	"net/url"
+	"crypto/subtle"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
-	"github.com/isd-sgcu/rpkm66-auth/client"
 	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
 	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	audit_entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
 	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
 	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
-	"github.com/isd-sgcu/rpkm66-auth/pkg/client/chula_sso"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
 	auth_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/auth"
+	audit_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/audit"
+	eligibility_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/eligibility"
+	permission_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	state_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/state"
 	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
 	user_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/user"
 	user_proto "github.com/isd-sgcu/rpkm66-go-proto/rpkm66/backend/user/v1"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/oauth2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 var _ auth_proto.AuthServiceServer = &serviceImpl{}
 
 type serviceImpl struct {
 	auth_proto.UnimplementedAuthServiceServer
-	repo              auth_repo.Repository
-	chulaSSOClient    chula_sso.ChulaSSO
-	tokenService      token_svc.Service
-	userService       user_svc.Service
-	conf              cfgldr.App
-	oauthConfig       *oauth2.Config
-	googleOauthClient *client.GoogleOauthClient
+	repo               auth_repo.Repository
+	providers          map[role.Provider]provider.IdentityProvider
+	tokenService       token_svc.Service
+	userService        user_svc.Service
+	permissionService  permission_svc.Service
+	eligibilityService eligibility_svc.Service
+	academicYear       *utils.AcademicYear
+	conf               cfgldr.App
+	stateService       state_svc.Service
+	auditService       audit_svc.Service
+	clientInfo         *utils.ClientInfo
 }
 
 func NewService(
 	repo auth_repo.Repository,
-	chulaSSOClient chula_sso.ChulaSSO,
+	providers []provider.IdentityProvider,
 	tokenService token_svc.Service,
 	userService user_svc.Service,
+	permissionService permission_svc.Service,
+	eligibilityService eligibility_svc.Service,
+	stateService state_svc.Service,
+	auditService audit_svc.Service,
+	clientInfo *utils.ClientInfo,
+	academicYear *utils.AcademicYear,
 	conf cfgldr.App,
-	oauthConfig *oauth2.Config,
-	googleOauthClient *client.GoogleOauthClient,
 ) *serviceImpl {
-	return &serviceImpl{
-		repo:              repo,
-		chulaSSOClient:    chulaSSOClient,
-		tokenService:      tokenService,
-		userService:       userService,
-		conf:              conf,
-		oauthConfig:       oauthConfig,
-		googleOauthClient: googleOauthClient,
+	s := &serviceImpl{
+		repo:               repo,
+		providers:          map[role.Provider]provider.IdentityProvider{},
+		tokenService:       tokenService,
+		userService:        userService,
+		permissionService:  permissionService,
+		eligibilityService: eligibilityService,
+		stateService:       stateService,
+		auditService:       auditService,
+		clientInfo:         clientInfo,
+		academicYear:       academicYear,
+		conf:               conf,
 	}
+
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+
+	return s
+}
+
+func (s *serviceImpl) VerifyTicket(ctx context.Context, req *auth_proto.VerifyTicketRequest) (res *auth_proto.VerifyTicketResponse, err error) {
+	credentials, err := s.login(ctx, role.CHULA_SSO, req.Ticket, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.VerifyTicketResponse{Credential: credentials}, nil
+}
+
+func (s *serviceImpl) Validate(_ context.Context, req *auth_proto.ValidateRequest) (res *auth_proto.ValidateResponse, err error) {
+	credential, err := s.tokenService.Validate(req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if credential.ClientId != "" {
+		return &auth_proto.ValidateResponse{
+			ClientId:    credential.ClientId,
+			Permissions: credential.Scopes,
+		}, nil
+	}
+
+	permissions, err := s.permissionService.FindByRole(credential.Role)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "validate").
+			Str("user_id", credential.UserId).
+			Msg("Error while querying the permissions")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.ValidateResponse{
+		UserId:      credential.UserId,
+		Role:        string(credential.Role),
+		Permissions: permissions,
+		Actor:       credential.Actor,
+	}, nil
+}
+
+func (s *serviceImpl) RefreshToken(ctx context.Context, req *auth_proto.RefreshTokenRequest) (res *auth_proto.RefreshTokenResponse, err error) {
+	refreshToken := entity.RefreshToken{}
+
+	err = s.repo.FindRefreshToken(utils.Hash([]byte(req.RefreshToken)), &refreshToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+	}
+
+	session := entity.Session{}
+
+	err = s.repo.FindSessionByID(refreshToken.SessionID.String(), &session)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+	}
+
+	// a rotated token is only presented again when it was stolen, so the whole family is no longer trusted
+	if refreshToken.RotatedAt != nil {
+		return nil, s.revokeReusedTokenFamily(ctx, &session)
+	}
+
+	now := time.Now()
+	if isSessionExpired(&session, now) {
+		return nil, status.Error(codes.Unauthenticated, "Refresh token is expired")
+	}
+
+	auth := entity.Auth{}
+
+	err = s.repo.FindByUserID(session.UserID, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	err = s.repo.RotateRefreshToken(refreshToken.ID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, s.revokeReusedTokenFamily(ctx, &session)
+		}
+
+		log.Error().Err(err).
+			Str("service", "auth").
+			Str("module", "refresh token").
+			Str("session_id", session.ID.String()).
+			Msg("Error while rotating the refresh token")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	session.LastRefreshedAt = &now
+
+	credentials, err := s.CreateNewCredential(&auth, &session, &refreshToken)
+	if err != nil {
+		log.Error().Err(err).
+			Str("service", "auth").
+			Str("module", "refresh token").
+			Msg("Error while create new token")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.TOKEN_REFRESH),
+		UserID:    auth.UserID,
+		StudentID: auth.StudentID,
+		Provider:  session.Provider,
+	})
+
+	return &auth_proto.RefreshTokenResponse{Credential: credentials}, nil
+}
+
+// IssueServiceToken is the client credentials grant of the backends, the token carries the requested scopes or every scope of the client
+func (s *serviceImpl) IssueServiceToken(_ context.Context, req *auth_proto.IssueServiceTokenRequest) (*auth_proto.IssueServiceTokenResponse, error) {
+	if req.ClientId == "" || req.ClientSecret == "" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid client")
+	}
+
+	client := entity.ServiceClient{}
+
+	err := s.repo.FindServiceClient(req.ClientId, &client)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "issue service token").
+			Str("client_id", req.ClientId).
+			Msg("Error querying the service client")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	if err != nil || subtle.ConstantTimeCompare([]byte(utils.Hash([]byte(req.ClientSecret))), []byte(client.SecretHash)) != 1 {
+		log.Warn().
+			Str("service", "auth").
+			Str("module", "issue service token").
+			Str("client_id", req.ClientId).
+			Msg("Someone is trying to get a service token with an invalid client")
+		return nil, status.Error(codes.Unauthenticated, "Invalid client")
+	}
+
+	allowed := utils.ParseScopes(client.Scopes)
+	scopes := allowed
+
+	if req.Scope != "" {
+		scopes = utils.ParseScopes(req.Scope)
+		for _, scope := range scopes {
+			if !utils.HasScope(allowed, scope) {
+				return nil, status.Error(codes.InvalidArgument, "Invalid scope")
+			}
+		}
+	}
+
+	res, err := s.tokenService.CreateServiceToken(client.ClientID, scopes)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "issue service token").
+			Str("client_id", client.ClientID).
+			Msg("Error while create new token")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "issue service token").
+		Str("event", "service_token_issue").
+		Str("client_id", client.ClientID).
+		Str("scope", res.Scope).
+		Msg("Service client get a token")
+
+	return res, nil
+}
+
+func (s *serviceImpl) Logout(ctx context.Context, req *auth_proto.LogoutRequest) (res *auth_proto.LogoutResponse, err error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// an impersonation token has no session row, only its cache entry has to go
+	if credential.Actor != "" {
+		err = s.tokenService.RemoveCredentials(credential.SessionId)
+	} else {
+		err = s.RemoveSession(credential.SessionId)
+	}
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "logout").
+			Str("user_id", credential.UserId).
+			Str("session_id", credential.SessionId).
+			Msg("Error revoking the session")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "logout").
+		Str("user_id", credential.UserId).
+		Str("actor", credential.Actor).
+		Msg("User logout from the service")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:   string(role.LOGOUT),
+		UserID:  credential.UserId,
+		ActorID: credential.Actor,
+	})
+
+	return &auth_proto.LogoutResponse{Success: true}, nil
+}
+
+func (s *serviceImpl) ListSessions(ctx context.Context, req *auth_proto.ListSessionsRequest) (res *auth_proto.ListSessionsResponse, err error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.findActiveSessions(credential.UserId)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "list sessions").
+			Str("user_id", credential.UserId).
+			Msg("Error while querying the sessions")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.ListSessionsResponse{Sessions: RawToDtoSessions(sessions, credential.SessionId)}, nil
+}
+
+func (s *serviceImpl) RevokeSession(ctx context.Context, req *auth_proto.RevokeSessionRequest) (res *auth_proto.RevokeSessionResponse, err error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session := entity.Session{}
+
+	err = s.repo.FindSessionByID(req.SessionId, &session)
+	if err != nil || session.UserID != credential.UserId {
+		return nil, status.Error(codes.NotFound, "not found session")
+	}
+
+	err = s.RemoveSession(req.SessionId)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "revoke session").
+			Str("user_id", credential.UserId).
+			Str("session_id", req.SessionId).
+			Msg("Error revoking the session")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "revoke session").
+		Str("user_id", credential.UserId).
+		Str("session_id", req.SessionId).
+		Msg("User revoke the session")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:    string(role.SESSION_REVOKE),
+		UserID:   credential.UserId,
+		Provider: session.Provider,
+		ActorID:  credential.Actor,
+		Detail:   req.SessionId,
+	})
+
+	return &auth_proto.RevokeSessionResponse{Success: true}, nil
+}
+
+func (s *serviceImpl) GetJwks(context.Context, *auth_proto.GetJwksRequest) (*auth_proto.GetJwksResponse, error) {
+	jwks := s.tokenService.GetJwks()
+
+	var keys []*auth_proto.Jwk
+	for _, key := range jwks.Keys {
+		keys = append(keys, &auth_proto.Jwk{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return &auth_proto.GetJwksResponse{Keys: keys}, nil
+}
+
+func (s *serviceImpl) Introspect(_ context.Context, req *auth_proto.IntrospectRequest) (*auth_proto.IntrospectResponse, error) {
+	introspection, err := s.tokenService.Introspect(req.Token)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.IntrospectResponse{
+		Active:    introspection.Active,
+		Exp:       introspection.ExpiresAt,
+		Iat:       introspection.IssuedAt,
+		Iss:       introspection.Issuer,
+		Sub:       introspection.Subject,
+		Role:      introspection.Role,
+		SessionId: introspection.SessionId,
+		TokenType: introspection.TokenType,
+		ClientId:  introspection.ClientId,
+		Scope:     introspection.Scope,
+		Act:       introspection.Actor,
+	}, nil
+}
+
+func (s *serviceImpl) GetUserRole(_ context.Context, req *auth_proto.GetUserRoleRequest) (*auth_proto.GetUserRoleResponse, error) {
+	auth := entity.Auth{}
+
+	err := s.repo.FindByUserID(req.UserId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	return &auth_proto.GetUserRoleResponse{
+		UserId: auth.UserID,
+		Role:   auth.Role,
+	}, nil
+}
+
+func (s *serviceImpl) SetUserRole(ctx context.Context, req *auth_proto.SetUserRoleRequest) (*auth_proto.SetUserRoleResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.IsValidRole(req.Role) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid role")
+	}
+
+	auth := entity.Auth{}
+
+	err = s.repo.FindByUserID(req.UserId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	oldRole := auth.Role
+	auth.Role = req.Role
+
+	err = s.repo.Update(auth.ID.String(), &auth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "set user role").
+			Str("user_id", req.UserId).
+			Msg("Error updating the role")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "set user role").
+		Str("event", "role_change").
+		Str("admin_id", credential.UserId).
+		Str("user_id", req.UserId).
+		Str("old_role", oldRole).
+		Str("new_role", req.Role).
+		Msg("Admin change the user role")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.ROLE_CHANGE),
+		UserID:    auth.UserID,
+		StudentID: auth.StudentID,
+		ActorID:   actorOf(credential),
+		Detail:    oldRole + " -> " + req.Role,
+	})
+
+	var sessions []*entity.Session
+
+	err = s.repo.FindSessionsByUserID(req.UserId, &sessions)
+	if err == nil {
+		for _, session := range sessions {
+			err = s.tokenService.UpdateRole(session.ID.String(), role.Role(req.Role))
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "set user role").
+			Str("user_id", req.UserId).
+			Msg("Error updating the role of the active sessions")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.SetUserRoleResponse{
+		UserId: auth.UserID,
+		Role:   auth.Role,
+	}, nil
+}
+
+func (s *serviceImpl) CheckPermission(ctx context.Context, req *auth_proto.CheckPermissionRequest) (*auth_proto.CheckPermissionResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Permission == "" {
+		return nil, status.Error(codes.InvalidArgument, "No permission is provided")
+	}
+
+	allowed, err := s.permissionService.HasPermission(credential.Role, req.Permission)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "check permission").
+			Str("user_id", credential.UserId).
+			Msg("Error while querying the permissions")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+func (s *serviceImpl) ImportRoleGrants(ctx context.Context, req *auth_proto.ImportRoleGrantsRequest) (*auth_proto.ImportRoleGrantsResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := utils.ParseRoleGrants(bytes.NewReader(req.Csv))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if len(grants) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "No role grant is provided")
+	}
+
+	var roleGrants []*entity.RoleGrant
+	for _, grant := range grants {
+		roleGrants = append(roleGrants, &entity.RoleGrant{
+			StudentID: grant.StudentID,
+			Role:      grant.Role,
+			GrantedBy: credential.UserId,
+		})
+	}
+
+	err = s.repo.UpsertRoleGrants(&roleGrants)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "import role grants").
+			Str("admin_id", credential.UserId).
+			Msg("Error saving the role grants")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "import role grants").
+		Str("event", "role_grant_import").
+		Str("admin_id", credential.UserId).
+		Int("count", len(roleGrants)).
+		Msg("Admin import the role grants")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:   string(role.ROLE_GRANT_IMPORT),
+		ActorID: actorOf(credential),
+		Detail:  strconv.Itoa(len(roleGrants)) + " grants",
+	})
+
+	return &auth_proto.ImportRoleGrantsResponse{Imported: int32(len(roleGrants))}, nil
+}
+
+func (s *serviceImpl) LinkIdentity(ctx context.Context, req *auth_proto.LinkIdentityRequest) (*auth_proto.LinkIdentityResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.providers[role.Provider(req.Provider)]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "Invalid provider")
+	}
+
+	if req.Subject == "" && req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "No subject or email is provided")
+	}
+
+	auth := entity.Auth{}
+
+	err = s.repo.FindByUserID(req.UserId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	err = s.checkNotLinked(req.Provider, req.Subject, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &entity.Identity{
+		Provider: req.Provider,
+		Subject:  req.Subject,
+		Email:    req.Email,
+		AuthID:   auth.ID,
+		LinkedBy: credential.UserId,
+	}
+
+	err = s.repo.CreateIdentity(identity)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "link identity").
+			Str("user_id", req.UserId).
+			Msg("Error linking the identity")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "link identity").
+		Str("event", "identity_link").
+		Str("admin_id", credential.UserId).
+		Str("user_id", req.UserId).
+		Str("provider", req.Provider).
+		Str("email", req.Email).
+		Msg("Admin link an identity")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.IDENTITY_LINK),
+		UserID:    auth.UserID,
+		StudentID: auth.StudentID,
+		Provider:  req.Provider,
+		ActorID:   actorOf(credential),
+		Detail:    req.Email,
+	})
+
+	return &auth_proto.LinkIdentityResponse{Identity: RawToDtoIdentity(identity)}, nil
+}
+
+// checkNotLinked is AlreadyExists when the subject, or the email of a pending link, already belongs to an account
+func (s *serviceImpl) checkNotLinked(provider string, subject string, email string) error {
+	var err error
+
+	existing := entity.Identity{}
+	if subject != "" {
+		err = s.repo.FindIdentity(provider, subject, &existing)
+	} else {
+		err = s.repo.FindPendingIdentityByEmail(provider, email, &existing)
+	}
+
+	if err == nil {
+		return status.Error(codes.AlreadyExists, "Identity is already linked")
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "link identity").
+			Str("provider", provider).
+			Msg("Error querying the linked identity")
+		return status.Error(codes.Internal, "Internal service error")
+	}
+
+	return nil
+}
+
+// CreateAccount provisions a staff, alumni or guest who has no student id, the account starts as a user linked to the given identity
+func (s *serviceImpl) CreateAccount(ctx context.Context, req *auth_proto.CreateAccountRequest) (*auth_proto.CreateAccountResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.providers[role.Provider(req.Provider)]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "Invalid provider")
+	}
+
+	if req.Subject == "" && req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "No subject or email is provided")
+	}
+
+	if req.Firstname == "" || req.Lastname == "" {
+		return nil, status.Error(codes.InvalidArgument, "No firstname or lastname is provided")
+	}
+
+	err = s.checkNotLinked(req.Provider, req.Subject, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.Create(&user_proto.User{
+		Firstname: req.Firstname,
+		Lastname:  req.Lastname,
+		Email:     req.Email,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "create account").
+			Str("provider", req.Provider).
+			Msg("Error creating the user")
+		return nil, status.Error(codes.Unavailable, "Service is down")
+	}
+
+	auth := &entity.Auth{
+		Role:   role.USER,
+		UserID: user.Id,
+	}
+
+	err = s.repo.Create(auth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "create account").
+			Str("user_id", user.Id).
+			Msg("Error creating the auth data")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	identity := &entity.Identity{
+		Provider: req.Provider,
+		Subject:  req.Subject,
+		Email:    req.Email,
+		AuthID:   auth.ID,
+		LinkedBy: credential.UserId,
+	}
+
+	err = s.repo.CreateIdentity(identity)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "create account").
+			Str("user_id", user.Id).
+			Msg("Error linking the identity")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "create account").
+		Str("event", "account_create").
+		Str("admin_id", credential.UserId).
+		Str("user_id", user.Id).
+		Str("provider", req.Provider).
+		Str("email", req.Email).
+		Msg("Admin create an account")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:    string(role.ACCOUNT_CREATE),
+		UserID:   user.Id,
+		Provider: req.Provider,
+		ActorID:  actorOf(credential),
+		Detail:   req.Email,
+	})
+
+	return &auth_proto.CreateAccountResponse{
+		UserId:   user.Id,
+		Identity: RawToDtoIdentity(identity),
+	}, nil
+}
+
+func (s *serviceImpl) UnlinkIdentity(ctx context.Context, req *auth_proto.UnlinkIdentityRequest) (*auth_proto.UnlinkIdentityResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.findIdentities(req.UserId, "unlink identity")
+	if err != nil {
+		return nil, err
+	}
+
+	var identity *entity.Identity
+	for _, in := range identities {
+		if in.ID.String() == req.IdentityId {
+			identity = in
+			break
+		}
+	}
+
+	if identity == nil {
+		return nil, status.Error(codes.NotFound, "Identity not found")
+	}
+
+	err = s.repo.DeleteIdentity(identity.ID.String())
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "unlink identity").
+			Str("user_id", req.UserId).
+			Msg("Error unlinking the identity")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "unlink identity").
+		Str("event", "identity_unlink").
+		Str("admin_id", credential.UserId).
+		Str("user_id", req.UserId).
+		Str("provider", identity.Provider).
+		Str("email", identity.Email).
+		Msg("Admin unlink an identity")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:    string(role.IDENTITY_UNLINK),
+		UserID:   req.UserId,
+		Provider: identity.Provider,
+		ActorID:  actorOf(credential),
+		Detail:   identity.Email,
+	})
+
+	return &auth_proto.UnlinkIdentityResponse{Success: true}, nil
+}
+
+func (s *serviceImpl) ListIdentities(_ context.Context, req *auth_proto.ListIdentitiesRequest) (*auth_proto.ListIdentitiesResponse, error) {
+	identities, err := s.findIdentities(req.UserId, "list identities")
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.ListIdentitiesResponse{Identities: RawToDtoIdentities(identities)}, nil
+}
+
+func (s *serviceImpl) findIdentities(userId string, module string) ([]*entity.Identity, error) {
+	auth := entity.Auth{}
+
+	err := s.repo.FindByUserID(userId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	var identities []*entity.Identity
+
+	err = s.repo.FindIdentitiesByAuthID(auth.ID.String(), &identities)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", module).
+			Str("user_id", userId).
+			Msg("Error querying the identities")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return identities, nil
+}
+
+// Impersonate lets an admin act as the user to reproduce their problem, every token it issues is logged with the reason
+func (s *serviceImpl) Impersonate(ctx context.Context, req *auth_proto.ImpersonateRequest) (*auth_proto.ImpersonateResponse, error) {
+	credential, err := getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.Actor != "" {
+		return nil, status.Error(codes.PermissionDenied, "Cannot impersonate while impersonating")
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "No reason is provided")
+	}
+
+	auth := entity.Auth{}
+
+	err = s.repo.FindByUserID(req.UserId, &auth)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	if auth.UserID == credential.UserId || auth.Role == string(role.ADMIN) {
+		return nil, status.Error(codes.PermissionDenied, "Cannot impersonate an admin")
+	}
+
+	impersonation, err := s.tokenService.CreateImpersonationCredentials(&auth, credential.UserId)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonate").
+			Str("user_id", req.UserId).
+			Msg("Error creating the impersonation credential")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	log.Info().
+		Str("service", "auth").
+		Str("module", "impersonate").
+		Str("event", "impersonation_start").
+		Str("admin_id", credential.UserId).
+		Str("user_id", req.UserId).
+		Str("reason", req.Reason).
+		Int32("expires_in", impersonation.ExpiresIn).
+		Msg("Admin impersonate the user")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.IMPERSONATION_START),
+		UserID:    auth.UserID,
+		StudentID: auth.StudentID,
+		ActorID:   credential.UserId,
+		Detail:    req.Reason,
+	})
+
+	return &auth_proto.ImpersonateResponse{Credential: impersonation}, nil
+}
+
+func (s *serviceImpl) ListAuditEvents(_ context.Context, req *auth_proto.ListAuditEventsRequest) (*auth_proto.ListAuditEventsResponse, error) {
+	if req.Since < 0 || req.Until < 0 || (req.Until > 0 && req.Until < req.Since) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid time range")
+	}
+
+	filter := &dto.AuditFilter{
+		Event:     req.Event,
+		UserID:    req.UserId,
+		StudentID: req.StudentId,
+		Provider:  req.Provider,
+		ActorID:   req.ActorId,
+		Page:      int(req.Page),
+		PageSize:  int(req.PageSize),
+	}
+
+	if req.Since > 0 {
+		filter.Since = time.Unix(req.Since, 0)
+	}
+
+	if req.Until > 0 {
+		filter.Until = time.Unix(req.Until, 0)
+	}
+
+	events, total, err := s.auditService.Find(filter)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "list audit events").
+			Msg("Error querying the audit events")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return &auth_proto.ListAuditEventsResponse{
+		Events:   RawToDtoAuditEvents(events),
+		Total:    total,
+		Page:     int32(filter.Page),
+		PageSize: int32(filter.PageSize),
+	}, nil
+}
+
+// getCredential returns the caller validated by the auth interceptor, the method must not be public in the policy table
+// actorOf is the admin behind the credential, an impersonation credential names the user so the actor is taken from it instead
+func actorOf(credential *dto.UserCredential) string {
+	if credential.Actor != "" {
+		return credential.Actor
+	}
+
+	return credential.UserId
+}
+
+func getCredential(ctx context.Context) (*dto.UserCredential, error) {
+	credential, ok := utils.GetCredential(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Missing token")
+	}
+
+	return credential, nil
+}
+
+func (s *serviceImpl) CreateNewSession(ctx context.Context, auth *entity.Auth, provider role.Provider) (*auth_proto.Credential, error) {
+	if s.conf.MaxSessions > 0 {
+		sessions, err := s.findActiveSessions(auth.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		// sessions are sorted from the oldest, evict them until there is room for the new one
+		for i := 0; i <= len(sessions)-s.conf.MaxSessions; i++ {
+			err = s.RemoveSession(sessions[i].ID.String())
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ipAddress, userAgent := s.clientInfo.Get(ctx)
+
+	session := entity.Session{
+		UserID:    auth.UserID,
+		Provider:  string(provider),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+
+	err := s.repo.CreateSession(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CreateNewCredential(auth, &session, nil)
+}
+
+func (s *serviceImpl) CreateNewCredential(auth *entity.Auth, session *entity.Session, parent *entity.RefreshToken) (*auth_proto.Credential, error) {
+	credentials, err := s.tokenService.CreateCredentials(auth, session, s.conf.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.UpdateSession(session.ID.String(), session)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := entity.RefreshToken{
+		SessionID: session.ID,
+		Token:     utils.Hash([]byte(credentials.RefreshToken)),
+	}
+
+	if parent != nil {
+		refreshToken.ParentID = &parent.ID
+	}
+
+	err = s.repo.CreateRefreshToken(&refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// initialRole returns the role granted to the student before the first login, a student without grant is a user
+func (s *serviceImpl) initialRole(studentId string) (string, error) {
+	grant := entity.RoleGrant{}
+
+	err := s.repo.FindRoleGrantByStudentID(studentId, &grant)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return role.USER, nil
+		}
+
+		return "", err
+	}
+
+	return grant.Role, nil
+}
+
+// SyncIdentity keeps the identity on the auth in line with the user service, it is embedded in the token on every refresh
+func (s *serviceImpl) SyncIdentity(auth *entity.Auth, user *user_proto.User) error {
+	if auth.StudentID == user.StudentID && auth.Faculty == user.Faculty && auth.Year == user.Year {
+		return nil
+	}
+
+	auth.StudentID = user.StudentID
+	auth.Faculty = user.Faculty
+	auth.Year = user.Year
+
+	return s.repo.Update(auth.ID.String(), auth)
 }
 
-func (s *serviceImpl) VerifyTicket(_ context.Context, req *auth_proto.VerifyTicketRequest) (res *auth_proto.VerifyTicketResponse, err error) {
-	ssoData := dto.ChulaSSOCredential{}
-	auth := entity.Auth{}
+// isSessionExpired is false for a zero time, the sessions created before the expiry columns existed are not capped until a refresh stamps them
+func isSessionExpired(session *entity.Session, now time.Time) bool {
+	return (!session.RefreshExpiresAt.IsZero() && now.After(session.RefreshExpiresAt)) || (!session.ExpiresAt.IsZero() && now.After(session.ExpiresAt))
+}
 
-	err = s.chulaSSOClient.VerifyTicket(req.Ticket, &ssoData)
+// findActiveSessions deletes the expired sessions of the user on the way, they can no longer be refreshed so they are neither listed nor counted against max_sessions
+func (s *serviceImpl) findActiveSessions(userId string) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+
+	err := s.repo.FindSessionsByUserID(userId, &sessions)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Str("service", "auth service").
-			Str("module", "verify ticket").
-			Msgf("Someone is trying to logging in using SSO ticket")
-		return nil, err
-	}
-
-	user, err := s.userService.FindByStudentID(ssoData.Ouid)
-	if err != nil {
-		st, ok := status.FromError(err)
-		if ok {
-			switch st.Code() {
-			case codes.NotFound:
-				year, err := utils.CalYearFromID(ssoData.Ouid)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "verify ticket").
-						Str("student_id", ssoData.Ouid).
-						Msg("Cannot parse year to to int")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				yearInt, err := strconv.Atoi(year)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "verify ticket").
-						Str("student_id", ssoData.Ouid).
-						Msg("Cannot parse student id to int")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				if yearInt > s.conf.MaxRestrictYear {
-					log.Error().
-						Str("service", "auth").
-						Str("module", "verify ticket").
-						Str("student_id", ssoData.Ouid).
-						Msg("Someone is trying to login (forbidden year)")
-					return nil, status.Error(codes.PermissionDenied, "Forbidden study year")
-				}
-
-				faculty, err := utils.GetFacultyFromID(ssoData.Ouid)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "verify ticket").
-						Str("student_id", ssoData.Ouid).
-						Msg("Cannot get faculty from student id")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				in := &user_proto.User{
-					Firstname: ssoData.Firstname,
-					Lastname:  ssoData.Lastname,
-					StudentID: ssoData.Ouid,
-					Year:      year,
-					Faculty:   faculty.FacultyEN,
-				}
-
-				user, err = s.userService.Create(in)
-				if err != nil {
-					return nil, status.Error(codes.InvalidArgument, st.Message())
-				}
-
-				auth = entity.Auth{
-					Role:   role.USER,
-					UserID: user.Id,
-				}
-
-				err = s.repo.Create(&auth)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "verify ticket").
-						Str("student_id", ssoData.Ouid).
-						Msg("Error creating the auth data")
-					return nil, status.Error(codes.Unavailable, st.Message())
-				}
-
-			default:
-				log.Error().
-					Err(err).
-					Str("service", "auth").
-					Str("module", "verify ticket").
-					Str("student_id", ssoData.Ouid).
-					Msg("Service is down")
-				return nil, status.Error(codes.Unavailable, st.Message())
-			}
-		} else {
-			log.Error().
-				Err(err).
-				Str("service", "auth").
-				Str("module", "verify ticket").
-				Str("student_id", ssoData.Ouid).
-				Msg("Error connect to sso")
-			return nil, status.Error(codes.Unavailable, "Service is down")
+		return nil, err
+	}
+
+	now := time.Now()
+	active := []*entity.Session{}
+
+	for _, session := range sessions {
+		if !isSessionExpired(session, now) {
+			active = append(active, session)
+			continue
 		}
-	} else {
-		err := s.repo.FindByUserID(user.Id, &auth)
+
+		err = s.RemoveSession(session.ID.String())
 		if err != nil {
-			return nil, status.Error(codes.NotFound, "not found user")
+			return nil, err
 		}
 	}
 
-	credentials, err := s.CreateNewCredential(&auth)
+	return active, nil
+}
+
+func (s *serviceImpl) RemoveSession(sessionId string) error {
+	err := s.tokenService.RemoveCredentials(sessionId)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return err
 	}
 
-	log.Info().
+	err = s.repo.DeleteRefreshTokensBySessionID(sessionId)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteSession(sessionId)
+}
+
+func (s *serviceImpl) revokeReusedTokenFamily(ctx context.Context, session *entity.Session) error {
+	log.Warn().
 		Str("service", "auth").
-		Str("module", "verify ticket").
-		Str("student_id", user.StudentID).
-		Msg("User login to the service")
+		Str("module", "refresh token").
+		Str("event", "refresh_token_reuse").
+		Str("user_id", session.UserID).
+		Str("session_id", session.ID.String()).
+		Msg("Rotated refresh token is reused, revoking the token family")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:    string(role.REFRESH_REUSE),
+		UserID:   session.UserID,
+		Provider: session.Provider,
+		Detail:   session.ID.String(),
+	})
+
+	err := s.RemoveSession(session.ID.String())
+	if err != nil {
+		log.Error().Err(err).
+			Str("service", "auth").
+			Str("module", "refresh token").
+			Str("session_id", session.ID.String()).
+			Msg("Error while revoking the token family")
+		return status.Error(codes.Internal, "Internal service error")
+	}
 
-	return &auth_proto.VerifyTicketResponse{Credential: credentials}, err
+	return status.Error(codes.Unauthenticated, "Invalid refresh token")
 }
 
-func (s *serviceImpl) Validate(_ context.Context, req *auth_proto.ValidateRequest) (res *auth_proto.ValidateResponse, err error) {
-	credential, err := s.tokenService.Validate(req.Token)
+func (s *serviceImpl) GetGoogleLoginUrl(context.Context, *auth_proto.GetGoogleLoginUrlRequest) (*auth_proto.GetGoogleLoginUrlResponse, error) {
+	url, state, err := s.loginUrl(string(role.GOOGLE))
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, err.Error())
+		return nil, err
 	}
 
-	return &auth_proto.ValidateResponse{
-		UserId: credential.UserId,
-		Role:   string(credential.Role),
+	return &auth_proto.GetGoogleLoginUrlResponse{
+		Url:   url,
+		State: state,
 	}, nil
 }
 
-func (s *serviceImpl) RefreshToken(_ context.Context, req *auth_proto.RefreshTokenRequest) (res *auth_proto.RefreshTokenResponse, err error) {
-	auth := entity.Auth{}
+func (s *serviceImpl) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyGoogleLoginRequest) (*auth_proto.VerifyGoogleLoginResponse, error) {
+	credentials, err := s.redirectLogin(ctx, string(role.GOOGLE), req.GetCode(), req.GetState())
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.VerifyGoogleLoginResponse{Credential: credentials}, nil
+}
 
-	err = s.repo.FindByRefreshToken(utils.Hash([]byte(req.RefreshToken)), &auth)
+func (s *serviceImpl) GetOidcLoginUrl(_ context.Context, req *auth_proto.GetOidcLoginUrlRequest) (*auth_proto.GetOidcLoginUrlResponse, error) {
+	url, state, err := s.loginUrl(req.Provider)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid refresh token")
+		return nil, err
 	}
 
-	credentials, err := s.CreateNewCredential(&auth)
+	return &auth_proto.GetOidcLoginUrlResponse{
+		Url:   url,
+		State: state,
+	}, nil
+}
+
+func (s *serviceImpl) VerifyOidcLogin(ctx context.Context, req *auth_proto.VerifyOidcLoginRequest) (*auth_proto.VerifyOidcLoginResponse, error) {
+	credentials, err := s.redirectLogin(ctx, req.Provider, req.Code, req.State)
 	if err != nil {
-		log.Error().Err(err).
-			Str("service", "auth").
-			Str("module", "refresh token").
-			Msg("Error while create new token")
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
-	return &auth_proto.RefreshTokenResponse{Credential: credentials}, nil
+	return &auth_proto.VerifyOidcLoginResponse{Credential: credentials}, nil
 }
 
-func (s *serviceImpl) CreateNewCredential(auth *entity.Auth) (*auth_proto.Credential, error) {
-	credentials, err := s.tokenService.CreateCredentials(auth, s.conf.Secret)
+func (s *serviceImpl) SendMagicLink(_ context.Context, req *auth_proto.SendMagicLinkRequest) (*auth_proto.SendMagicLinkResponse, error) {
+	idp, ok := s.providers[role.EMAIL].(provider.MagicLinkProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "Provider is not supported")
+	}
+
+	err := idp.SendLink(req.Email)
 	if err != nil {
 		return nil, err
 	}
 
-	auth.RefreshToken = utils.Hash([]byte(credentials.RefreshToken))
+	return &auth_proto.SendMagicLinkResponse{Success: true}, nil
+}
 
-	err = s.repo.Update(auth.ID.String(), auth)
+func (s *serviceImpl) VerifyMagicLink(ctx context.Context, req *auth_proto.VerifyMagicLinkRequest) (*auth_proto.VerifyMagicLinkResponse, error) {
+	credentials, err := s.login(ctx, role.EMAIL, req.Token, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return credentials, nil
+	return &auth_proto.VerifyMagicLinkResponse{Credential: credentials}, nil
 }
 
-func (s *serviceImpl) GetGoogleLoginUrl(context.Context, *auth_proto.GetGoogleLoginUrlRequest) (*auth_proto.GetGoogleLoginUrlResponse, error) {
-	URL, err := url.Parse(s.oauthConfig.Endpoint.AuthURL)
+func (s *serviceImpl) redirectProvider(name string) (provider.RedirectProvider, error) {
+	idp, ok := s.providers[role.Provider(name)].(provider.RedirectProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "Provider is not supported")
+	}
+
+	return idp, nil
+}
+
+// loginUrl starts a redirect login, the state, nonce and PKCE verifier are kept in the cache until the callback
+func (s *serviceImpl) loginUrl(name string) (string, string, error) {
+	idp, err := s.redirectProvider(name)
 	if err != nil {
-		log.Error().Err(err).Msg("unable to parse url")
-		return nil, status.Error(codes.Internal, "Internal server error")
+		return "", "", err
 	}
-	parameters := url.Values{}
-	parameters.Add("client_id", s.oauthConfig.ClientID)
-	parameters.Add("scope", strings.Join(s.oauthConfig.Scopes, " "))
-	parameters.Add("redirect_uri", s.oauthConfig.RedirectURL)
-	parameters.Add("response_type", "code")
-	URL.RawQuery = parameters.Encode()
-	url := URL.String()
 
-	return &auth_proto.GetGoogleLoginUrlResponse{
-		Url: url,
-	}, nil
+	loginState, err := s.stateService.Create(idp.Name())
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "login url").
+			Str("provider", name).
+			Msg("Error creating the login state")
+		return "", "", status.Error(codes.Internal, "Internal service error")
+	}
+
+	return idp.LoginUrl(loginState), loginState.State, nil
 }
 
-func (s *serviceImpl) VerifyGoogleLogin(ctx context.Context, req *auth_proto.VerifyGoogleLoginRequest) (*auth_proto.VerifyGoogleLoginResponse, error) {
-	code := req.GetCode()
-	auth := entity.Auth{}
+// redirectLogin consumes the state before exchanging the code, so a state can never be used twice
+func (s *serviceImpl) redirectLogin(ctx context.Context, name string, code string, state string) (*auth_proto.Credential, error) {
+	idp, err := s.redirectProvider(name)
+	if err != nil {
+		return nil, err
+	}
 
 	if code == "" {
 		return nil, status.Error(codes.InvalidArgument, "No code is provided")
 	}
 
-	response, err := s.googleOauthClient.GetUserEmail(code)
+	loginState, err := s.stateService.Consume(idp.Name(), state)
 	if err != nil {
-		switch err.Error() {
-		case "Invalid code":
-			return nil, status.Error(codes.InvalidArgument, "Invalid code")
-		default:
-			log.Error().Err(err).Msg("Unable to get user info")
-			return nil, status.Error(codes.Internal, "Internal server error")
+		if err == state_svc.ErrInvalidState {
+			err = status.Error(codes.InvalidArgument, "Invalid state")
+			s.recordLoginFailure(ctx, idp.Name(), "", err)
+			return nil, err
 		}
+
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "login").
+			Str("provider", name).
+			Msg("Error consuming the login state")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return s.login(ctx, idp.Name(), code, loginState)
+}
+
+// login resolves the proof with the identity provider then provisions the user and opens a new session
+func (s *serviceImpl) login(ctx context.Context, name role.Provider, proof string, state *dto.LoginState) (*auth_proto.Credential, error) {
+	idp, ok := s.providers[name]
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "Provider is not supported")
+	}
+
+	identity, err := idp.Resolve(proof, state)
+	if err != nil {
+		s.recordLoginFailure(ctx, name, "", err)
+		return nil, err
+	}
+
+	auth, err := s.provision(identity)
+	if err != nil {
+		s.recordLoginFailure(ctx, name, identity.StudentID, err)
+		return nil, err
+	}
+
+	credentials, err := s.CreateNewSession(ctx, auth, identity.Provider)
+	if err != nil {
+		err = status.Error(codes.Internal, err.Error())
+		s.recordLoginFailure(ctx, name, identity.StudentID, err)
+		return nil, err
 	}
 
-	email := response.Email
+	log.Info().
+		Str("service", "auth").
+		Str("module", "login").
+		Str("provider", string(identity.Provider)).
+		Str("student_id", identity.StudentID).
+		Msg("User login to the service")
+
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.LOGIN_SUCCESS),
+		UserID:    auth.UserID,
+		StudentID: identity.StudentID,
+		Provider:  string(identity.Provider),
+	})
+
+	return credentials, nil
+}
+
+// recordLoginFailure keeps the reason given to the caller as the detail, the ErrorInfo reason of a denial when there is one
+// since several denials share a message, the cause of an internal error is only in the log
+func (s *serviceImpl) recordLoginFailure(ctx context.Context, name role.Provider, studentId string, err error) {
+	st := status.Convert(err)
+
+	detail := st.Message()
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Reason != "" {
+			detail = info.Reason
+			break
+		}
+	}
 
-	log.Info().Interface("response", response).Msg("kuy")
+	s.auditService.Record(ctx, &audit_entity.AuditEvent{
+		Event:     string(role.LOGIN_FAILURE),
+		StudentID: studentId,
+		Provider:  string(name),
+		Detail:    detail,
+	})
+}
 
-	ouid, err := utils.GetOuidFromGmail(email)
+// provision resolves the account of the identity, a linked identity wins over the student id so that every provider of a person lands on the same account
+func (s *serviceImpl) provision(identity *dto.Identity) (*entity.Auth, error) {
+	auth, err := s.findLinkedAuth(identity)
 	if err != nil {
+		return nil, err
+	}
+
+	if auth != nil {
+		if auth.StudentID != "" && auth.Role == role.USER {
+			if err := s.checkEligibility(identity, auth.StudentID); err != nil {
+				return nil, err
+			}
+		}
+
+		return auth, nil
+	}
+
+	if identity.StudentID == "" {
+		log.Info().
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("email", identity.Email).
+			Msg("Someone is trying to login with an identity that is not linked")
 		return nil, status.Error(codes.PermissionDenied, "Only chula student can login")
 	}
-	firstName := response.Firstname
-	familyName := response.Lastname
-
-	user, err := s.userService.FindByStudentID(ouid)
-	if err != nil {
-		st, ok := status.FromError(err)
-		if ok {
-			switch st.Code() {
-			case codes.NotFound:
-				year, err := utils.CalYearFromID(ouid)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "google").
-						Str("student_id", ouid).
-						Msg("Cannot parse year to to int")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				yearInt, err := strconv.Atoi(year)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "google").
-						Str("student_id", ouid).
-						Msg("Cannot parse student id to int")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				if yearInt > s.conf.MaxRestrictYear {
-					log.Error().
-						Str("service", "auth").
-						Str("module", "google").
-						Str("student_id", ouid).
-						Msg("Someone is trying to login (forbidden year)")
-					return nil, status.Error(codes.PermissionDenied, "Forbidden study year")
-				}
-
-				faculty, err := utils.GetFacultyFromID(ouid)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "google").
-						Str("student_id", ouid).
-						Msg("Cannot get faculty from student id")
-					return nil, status.Error(codes.Internal, "Internal service error")
-				}
-
-				in := &user_proto.User{
-					Firstname: firstName,
-					Lastname:  familyName,
-					StudentID: ouid,
-					Year:      year,
-					Faculty:   faculty.FacultyEN,
-				}
-
-				user, err = s.userService.Create(in)
-				if err != nil {
-					return nil, status.Error(codes.InvalidArgument, st.Message())
-				}
-
-				auth = entity.Auth{
-					Role:   role.USER,
-					UserID: user.Id,
-				}
-
-				err = s.repo.Create(&auth)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "auth").
-						Str("module", "google").
-						Str("student_id", ouid).
-						Msg("Error creating the auth data")
-					return nil, status.Error(codes.Unavailable, st.Message())
-				}
-
-			default:
-				log.Error().
-					Err(err).
-					Str("service", "auth").
-					Str("module", "google").
-					Str("student_id", ouid).
-					Msg("Service is down")
-				return nil, status.Error(codes.Unavailable, st.Message())
+
+	auth, err = s.provisionStudent(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.Subject == "" {
+		return auth, nil
+	}
+
+	link := &entity.Identity{
+		Provider: string(identity.Provider),
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		AuthID:   auth.ID,
+	}
+
+	err = s.repo.CreateIdentity(link)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Error linking the identity")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	return auth, nil
+}
+
+// findLinkedAuth returns nil without an error when the identity is not linked to any account,
+// an identity linked by email gets its subject on the first login, but only when the provider verified the email,
+// otherwise anyone who can set that email on their account would take over the link
+func (s *serviceImpl) findLinkedAuth(identity *dto.Identity) (*entity.Auth, error) {
+	if identity.Subject == "" {
+		return nil, nil
+	}
+
+	link := entity.Identity{}
+
+	err := s.repo.FindIdentity(string(identity.Provider), identity.Subject, &link)
+	if errors.Is(err, gorm.ErrRecordNotFound) && identity.Email != "" && identity.EmailVerified {
+		err = s.repo.FindPendingIdentityByEmail(string(identity.Provider), identity.Email, &link)
+		if err == nil {
+			link.Subject = identity.Subject
+			err = s.repo.UpdateIdentity(link.ID.String(), &link)
+		}
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Msg("Error querying the linked identity")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	auth := entity.Auth{}
+
+	err = s.repo.FindByID(link.AuthID.String(), &auth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("auth_id", link.AuthID.String()).
+			Msg("Linked identity has no account")
+		return nil, status.Error(codes.NotFound, "not found user")
+	}
+
+	return &auth, nil
+}
+
+// checkEligibility runs on every login of a student account with the user role, not only the first, so a denylisted student or a closed login window also stops the existing users,
+// admins and staff are never checked so the rules cannot lock out the people running the event
+func (s *serviceImpl) checkEligibility(identity *dto.Identity, studentId string) error {
+	err := s.eligibilityService.Check(studentId)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", studentId).
+			Msg("Someone is trying to login (not eligible)")
+	}
+
+	return err
+}
+
+// provisionStudent finds the auth data of the student, the user is created on the first login
+func (s *serviceImpl) provisionStudent(identity *dto.Identity) (*entity.Auth, error) {
+	auth := entity.Auth{}
+
+	user, err := s.userService.FindByStudentID(identity.StudentID)
+	if err == nil {
+		err = s.repo.FindByUserID(user.Id, &auth)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, "not found user")
+		}
+
+		if auth.Role == role.USER {
+			if err := s.checkEligibility(identity, identity.StudentID); err != nil {
+				return nil, err
 			}
-		} else {
+		}
+
+		err = s.SyncIdentity(&auth, user)
+		if err != nil {
 			log.Error().
 				Err(err).
 				Str("service", "auth").
-				Str("module", "google").
-				Str("student_id", ouid).
-				Msg("Error connect to sso")
-			return nil, status.Error(codes.Unavailable, "Service is down")
+				Str("module", "provision").
+				Str("provider", string(identity.Provider)).
+				Str("student_id", identity.StudentID).
+				Msg("Error updating the auth data")
+			return nil, status.Error(codes.Internal, "Internal service error")
 		}
-	} else {
-		err := s.repo.FindByUserID(user.Id, &auth)
-		if err != nil {
-			return nil, status.Error(codes.NotFound, "not found user")
+
+		return &auth, nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Error connect to the user service")
+		return nil, status.Error(codes.Unavailable, "Service is down")
+	}
+
+	if st.Code() != codes.NotFound {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Service is down")
+		return nil, status.Error(codes.Unavailable, st.Message())
+	}
+
+	// a magic link carries no name to create the user with, the student signs up through another provider first and the email logs in to that account
+	if identity.Provider == role.EMAIL {
+		log.Info().
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Someone is trying to create an account by email")
+		return nil, status.Error(codes.PermissionDenied, "Sign up with Chula SSO before logging in by email")
+	}
+
+	initialRole, err := s.initialRole(identity.StudentID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Error querying the role grant")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	// a student pre-provisioned with a role is let in regardless of the eligibility rules, the grant is the allowlist entry
+	if initialRole == role.USER {
+		if err := s.checkEligibility(identity, identity.StudentID); err != nil {
+			return nil, err
 		}
 	}
 
-	credentials, err := s.CreateNewCredential(&auth)
+	year, err := s.academicYear.CalYearFromID(identity.StudentID)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Cannot parse year to to int")
+		return nil, status.Error(codes.Internal, "Internal service error")
 	}
 
-	log.Info().
-		Str("service", "auth").
-		Str("module", "verify ticket").
-		Str("student_id", user.StudentID).
-		Msg("User login to the service")
+	faculty, err := utils.GetFacultyFromID(identity.StudentID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Cannot get faculty from student id")
+		return nil, status.Error(codes.Internal, "Internal service error")
+	}
+
+	in := &user_proto.User{
+		Firstname: identity.Firstname,
+		Lastname:  identity.Lastname,
+		StudentID: identity.StudentID,
+		Year:      year,
+		Faculty:   faculty.FacultyEN,
+	}
+
+	user, err = s.userService.Create(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, st.Message())
+	}
 
-	return &auth_proto.VerifyGoogleLoginResponse{Credential: credentials}, err
+	auth = entity.Auth{
+		Role:      initialRole,
+		UserID:    user.Id,
+		StudentID: user.StudentID,
+		Faculty:   user.Faculty,
+		Year:      user.Year,
+	}
+
+	err = s.repo.Create(&auth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "provision").
+			Str("provider", string(identity.Provider)).
+			Str("student_id", identity.StudentID).
+			Msg("Error creating the auth data")
+		return nil, status.Error(codes.Unavailable, st.Message())
+	}
+
+	return &auth, nil
+}
+
+func RawToDtoSessions(in []*entity.Session, currentSessionId string) []*auth_proto.Session {
+	var result []*auth_proto.Session
+	for _, session := range in {
+		result = append(result, RawToDtoSession(session, currentSessionId))
+	}
+
+	return result
+}
+
+func RawToDtoSession(in *entity.Session, currentSessionId string) *auth_proto.Session {
+	session := &auth_proto.Session{
+		Id:        in.ID.String(),
+		Provider:  in.Provider,
+		CreatedAt: in.CreatedAt.Unix(),
+		IpAddress: in.IPAddress,
+		UserAgent: in.UserAgent,
+		Current:   in.ID.String() == currentSessionId,
+		ExpiresAt: in.ExpiresAt.Unix(),
+	}
+
+	if in.LastRefreshedAt != nil {
+		session.LastRefreshedAt = in.LastRefreshedAt.Unix()
+	}
+
+	return session
+}
+
+func RawToDtoIdentities(in []*entity.Identity) []*auth_proto.Identity {
+	var result []*auth_proto.Identity
+	for _, identity := range in {
+		result = append(result, RawToDtoIdentity(identity))
+	}
+
+	return result
+}
+
+func RawToDtoIdentity(in *entity.Identity) *auth_proto.Identity {
+	return &auth_proto.Identity{
+		Id:        in.ID.String(),
+		Provider:  in.Provider,
+		Subject:   in.Subject,
+		Email:     in.Email,
+		LinkedBy:  in.LinkedBy,
+		CreatedAt: in.CreatedAt.Unix(),
+	}
+}
+
+func RawToDtoAuditEvents(in []*audit_entity.AuditEvent) []*auth_proto.AuditEvent {
+	var result []*auth_proto.AuditEvent
+	for _, event := range in {
+		result = append(result, RawToDtoAuditEvent(event))
+	}
+
+	return result
+}
+
+func RawToDtoAuditEvent(in *audit_entity.AuditEvent) *auth_proto.AuditEvent {
+	return &auth_proto.AuditEvent{
+		Id:        in.ID.String(),
+		Event:     in.Event,
+		UserId:    in.UserID,
+		StudentId: in.StudentID,
+		Provider:  in.Provider,
+		ActorId:   in.ActorID,
+		Detail:    in.Detail,
+		ClientIp:  in.ClientIP,
+		UserAgent: in.UserAgent,
+		CreatedAt: in.CreatedAt.Unix(),
+	}
 }
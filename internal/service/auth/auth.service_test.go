@@ -13,15 +13,22 @@ import (
 	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
 	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
 	"github.com/isd-sgcu/rpkm66-auth/internal/entity"
+	audit_entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
 	"github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/chula_sso"
+	email_provider "github.com/isd-sgcu/rpkm66-auth/internal/provider/email"
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/google"
 	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
 	mock "github.com/isd-sgcu/rpkm66-auth/mocks/auth"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+	state_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/state"
 	user_proto "github.com/isd-sgcu/rpkm66-go-proto/rpkm66/backend/user/v1"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
-	"golang.org/x/oauth2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
@@ -29,17 +36,23 @@ import (
 
 type AuthServiceTest struct {
 	suite.Suite
-	Auth              *auth.Auth
-	UserDto           *user_proto.User
-	Credential        *auth_proto.Credential
-	Payload           *dto.TokenPayloadAuth
-	UserCredential    *dto.UserCredential
-	conf              cfgldr.App
-	oauthConf         oauth2.Config
-	googleOauthClient *client.GoogleOauthClient
-	UnauthorizedErr   error
-	NotFoundErr       error
-	ServiceDownErr    error
+	Auth               *auth.Auth
+	Session            *auth.Session
+	Sessions           []*auth.Session
+	RefreshToken       *auth.RefreshToken
+	UserDto            *user_proto.User
+	Credential         *auth_proto.Credential
+	Payload            *dto.TokenPayloadAuth
+	UserCredential     *dto.UserCredential
+	conf               cfgldr.App
+	academicYear       *utils.AcademicYear
+	auditService       *mock.AuditServiceMock
+	eligibilityService *mock.EligibilityServiceMock
+	clientInfo         *utils.ClientInfo
+	LoginState         *dto.LoginState
+	UnauthorizedErr    error
+	NotFoundErr        error
+	ServiceDownErr     error
 }
 
 func TestAuthService(t *testing.T) {
@@ -54,9 +67,44 @@ func (t *AuthServiceTest) SetupTest() {
 			UpdatedAt: time.Time{},
 			DeletedAt: gorm.DeletedAt{},
 		},
-		UserID:       faker.UUIDDigit(),
-		Role:         role.USER,
-		RefreshToken: faker.Word(),
+		UserID: faker.UUIDDigit(),
+		Role:   role.USER,
+	}
+
+	t.Session = &auth.Session{
+		Base: entity.Base{
+			ID:        uuid.New(),
+			CreatedAt: time.Time{},
+			UpdatedAt: time.Time{},
+			DeletedAt: gorm.DeletedAt{},
+		},
+		UserID:           t.Auth.UserID,
+		Provider:         string(role.CHULA_SSO),
+		IPAddress:        faker.IPv4(),
+		UserAgent:        faker.Word(),
+		RefreshExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+		ExpiresAt:        time.Now().Add(30 * 24 * time.Hour),
+	}
+
+	lastRefreshedAt := time.Now()
+
+	t.Sessions = []*auth.Session{
+		t.Session,
+		{
+			Base: entity.Base{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				DeletedAt: gorm.DeletedAt{},
+			},
+			UserID:           t.Auth.UserID,
+			Provider:         role.GOOGLE,
+			IPAddress:        faker.IPv4(),
+			UserAgent:        faker.Word(),
+			LastRefreshedAt:  &lastRefreshedAt,
+			RefreshExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+			ExpiresAt:        time.Now().Add(30 * 24 * time.Hour),
+		},
 	}
 
 	t.UserDto = &user_proto.User{
@@ -78,24 +126,41 @@ func (t *AuthServiceTest) SetupTest() {
 		CanSelectBaan:   true,
 	}
 
+	t.Auth.StudentID = t.UserDto.StudentID
+	t.Auth.Faculty = t.UserDto.Faculty
+	t.Auth.Year = t.UserDto.Year
+
 	t.Credential = &auth_proto.Credential{
 		AccessToken:  faker.Word(),
-		RefreshToken: t.Auth.RefreshToken,
+		RefreshToken: faker.Word(),
 		ExpiresIn:    3600,
 	}
 
+	t.RefreshToken = &auth.RefreshToken{
+		Base: entity.Base{
+			ID:        uuid.New(),
+			CreatedAt: time.Time{},
+			UpdatedAt: time.Time{},
+			DeletedAt: gorm.DeletedAt{},
+		},
+		SessionID: t.Session.ID,
+		Token:     utils.Hash([]byte(faker.Word())),
+	}
+
 	t.Payload = &dto.TokenPayloadAuth{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    faker.Word(),
 			ExpiresAt: jwt.NewNumericDate(time.Now()),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
-		UserId: t.Auth.UserID,
+		UserId:    t.Auth.UserID,
+		SessionId: t.Session.ID.String(),
 	}
 
 	t.UserCredential = &dto.UserCredential{
-		UserId: t.Auth.UserID,
-		Role:   role.Role(t.Auth.Role),
+		UserId:    t.Auth.UserID,
+		SessionId: t.Session.ID.String(),
+		Role:      role.Role(t.Auth.Role),
 	}
 
 	t.UnauthorizedErr = errors.New("unauthorized")
@@ -103,13 +168,60 @@ func (t *AuthServiceTest) SetupTest() {
 	t.ServiceDownErr = errors.New("service is down")
 
 	t.conf = cfgldr.App{
-		Port:            3001,
-		Debug:           false,
-		Secret:          "asuperstrong32bitpasswordgohere!",
-		MaxRestrictYear: 3,
+		Port:   3001,
+		Debug:  false,
+		Secret: "asuperstrong32bitpasswordgohere!",
+	}
+
+	t.academicYear, _ = utils.NewAcademicYear("", 2566, nil)
+	t.clientInfo, _ = utils.NewClientInfo(nil)
+
+	t.auditService = &mock.AuditServiceMock{}
+	t.auditService.On("Record", testify.Anything, testify.Anything).Return()
+
+	t.eligibilityService = &mock.EligibilityServiceMock{}
+	t.eligibilityService.On("Check", testify.Anything).Return(nil)
+
+	t.LoginState = &dto.LoginState{
+		State:        faker.Word(),
+		Nonce:        faker.Word(),
+		CodeVerifier: faker.Word(),
+		Provider:     role.GOOGLE,
+	}
+}
+
+func (t *AuthServiceTest) newProviders(chulaSSOClient *mock.ChulaSSOClientMock) []provider.IdentityProvider {
+	return []provider.IdentityProvider{chula_sso.NewProvider(chulaSSOClient)}
+}
+
+// unlinkedIdentity mocks the identity lookups of a login that has not been linked to any account yet
+func (t *AuthServiceTest) unlinkedIdentity(repo *mock.RepositoryMock) {
+	repo.On("FindIdentity", testify.Anything, testify.Anything, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindPendingIdentityByEmail", testify.Anything, testify.Anything, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("CreateIdentity", testify.Anything).Return(nil, nil)
+}
+
+func (t *AuthServiceTest) eligibilityDenial(reason role.DenialReason) error {
+	st, _ := status.New(codes.PermissionDenied, "Forbidden study year").WithDetails(&errdetails.ErrorInfo{
+		Reason: string(reason),
+		Domain: role.ELIGIBILITY_DOMAIN,
+	})
+
+	return st.Err()
+}
+
+func (t *AuthServiceTest) newRefreshToken(parentID *uuid.UUID) *auth.RefreshToken {
+	return &auth.RefreshToken{
+		SessionID: t.Session.ID,
+		ParentID:  parentID,
+		Token:     utils.Hash([]byte(t.Credential.RefreshToken)),
 	}
+}
 
-	t.oauthConf = oauth2.Config{}
+func (t *AuthServiceTest) refreshedSession() interface{} {
+	return testify.MatchedBy(func(in *auth.Session) bool {
+		return in.ID == t.Session.ID && in.LastRefreshedAt != nil
+	})
 }
 
 func (t *AuthServiceTest) TestVerifyTicketSuccessFirstTimeLogin() {
@@ -117,8 +229,6 @@ func (t *AuthServiceTest) TestVerifyTicketSuccessFirstTimeLogin() {
 		Credential: t.Credential,
 	}
 
-	t.Auth.RefreshToken = utils.Hash([]byte(t.Auth.RefreshToken))
-
 	ticket := faker.Word()
 	chulaSSORes := &dto.ChulaSSOCredential{
 		UID:         faker.Word(),
@@ -135,13 +245,20 @@ func (t *AuthServiceTest) TestVerifyTicketSuccessFirstTimeLogin() {
 	}
 
 	a := &auth.Auth{
-		UserID: t.UserDto.Id,
-		Role:   role.USER,
+		UserID:    t.UserDto.Id,
+		Role:      role.USER,
+		StudentID: t.UserDto.StudentID,
+		Faculty:   t.UserDto.Faculty,
+		Year:      t.UserDto.Year,
 	}
 
 	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindRoleGrantByStudentID", t.UserDto.StudentID, &auth.RoleGrant{}).Return(nil, gorm.ErrRecordNotFound)
 	repo.On("Create", a).Return(t.Auth, nil)
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
 	chulaSSOClient := &mock.ChulaSSOClientMock{}
 	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(chulaSSORes, nil)
@@ -159,90 +276,145 @@ func (t *AuthServiceTest) TestVerifyTicketSuccessFirstTimeLogin() {
 	userService.On("Create", in).Return(t.UserDto, nil)
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
 	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, actual)
+	repo.AssertNumberOfCalls(t.T(), "CreateIdentity", 1)
 }
 
-func (t *AuthServiceTest) TestVerifyTicketSuccessNotFirstTimeLogin() {
+func (t *AuthServiceTest) TestVerifyTicketFirstTimeLoginWithRoleGrant() {
 	want := &auth_proto.VerifyTicketResponse{
 		Credential: t.Credential,
 	}
 
 	ticket := faker.Word()
 
-	t.Auth.RefreshToken = utils.Hash([]byte(t.Auth.RefreshToken))
-
-	repo := &mock.RepositoryMock{}
-	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	staff := *t.Auth
+	staff.Role = role.BAAN_STAFF
 
-	chulaSSORes := &dto.ChulaSSOCredential{
-		UID:         faker.Word(),
-		Username:    faker.Username(),
-		Gecos:       faker.Username(),
-		Email:       faker.Email(),
-		Disable:     false,
-		Roles:       []string{"student"},
-		Firstname:   t.UserDto.Firstname,
-		Lastname:    t.UserDto.Lastname,
-		FirstnameTH: faker.FirstName(),
-		LastnameTH:  faker.LastName(),
-		Ouid:        t.UserDto.StudentID,
+	a := &auth.Auth{
+		UserID:    t.UserDto.Id,
+		Role:      role.BAAN_STAFF,
+		StudentID: t.UserDto.StudentID,
+		Faculty:   t.UserDto.Faculty,
+		Year:      t.UserDto.Year,
 	}
 
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindRoleGrantByStudentID", t.UserDto.StudentID, &auth.RoleGrant{}).Return(&auth.RoleGrant{StudentID: t.UserDto.StudentID, Role: role.BAAN_STAFF}, nil)
+	repo.On("Create", a).Return(&staff, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
 	chulaSSOClient := &mock.ChulaSSOClientMock{}
-	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(chulaSSORes, nil)
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+		Ouid:      t.UserDto.StudentID,
+	}, nil)
 
 	userService := &mock.UserServiceMock{}
-	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(nil, status.Error(codes.NotFound, "not found user"))
+	userService.On("Create", &user_proto.User{
+		StudentID: t.UserDto.StudentID,
+		Faculty:   t.UserDto.Faculty,
+		Year:      t.UserDto.Year,
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+	}).Return(t.UserDto, nil)
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	tokenService.On("CreateCredentials", &staff, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
 	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, actual)
 }
 
-func (t *AuthServiceTest) TestVerifyTicketInvalid() {
+func (t *AuthServiceTest) TestVerifyTicketRoleGrantSkipsEligibility() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
+	}
+
 	ticket := faker.Word()
 
+	staff := *t.Auth
+	staff.Role = role.BAAN_STAFF
+
+	a := &auth.Auth{
+		UserID:    t.UserDto.Id,
+		Role:      role.BAAN_STAFF,
+		StudentID: t.UserDto.StudentID,
+		Faculty:   t.UserDto.Faculty,
+		Year:      t.UserDto.Year,
+	}
+
 	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindRoleGrantByStudentID", t.UserDto.StudentID, &auth.RoleGrant{}).Return(&auth.RoleGrant{StudentID: t.UserDto.StudentID, Role: role.BAAN_STAFF}, nil)
+	repo.On("Create", a).Return(&staff, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
 	chulaSSOClient := &mock.ChulaSSOClientMock{}
-	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(nil, status.Error(codes.Unauthenticated, "Cannot connect to chula sso"))
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+		Ouid:      t.UserDto.StudentID,
+	}, nil)
 
 	userService := &mock.UserServiceMock{}
-	userService.On("FindByStudentID", t.UserDto.Id).Return(nil, t.NotFoundErr)
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(nil, status.Error(codes.NotFound, "not found user"))
+	userService.On("Create", &user_proto.User{
+		StudentID: t.UserDto.StudentID,
+		Faculty:   t.UserDto.Faculty,
+		Year:      t.UserDto.Year,
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+	}).Return(t.UserDto, nil)
 
 	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", &staff, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
-	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.YEAR_NOT_ALLOWED))
 
-	st, ok := status.FromError(err)
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
 
-	assert.True(t.T(), ok)
-	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	eligibilityService.AssertNotCalled(t.T(), "Check", testify.Anything)
 }
 
-func (t *AuthServiceTest) TestVerifyForbiddenYear() {
-	ticket := faker.Word()
+func (t *AuthServiceTest) TestVerifyTicketSuccessNotFirstTimeLogin() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
+	}
 
-	t.UserDto.StudentID = "60xxxxxx21"
-	t.Auth.RefreshToken = utils.Hash([]byte(t.Auth.RefreshToken))
+	ticket := faker.Word()
 
 	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
 	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
 	chulaSSORes := &dto.ChulaSSOCredential{
 		UID:         faker.Word(),
@@ -262,202 +434,1796 @@ func (t *AuthServiceTest) TestVerifyForbiddenYear() {
 	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(chulaSSORes, nil)
 
 	userService := &mock.UserServiceMock{}
-	userService.On("FindByStudentID", t.UserDto.StudentID).Return(nil, status.Error(codes.NotFound, "not found user"))
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
 	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
-	st, ok := status.FromError(err)
 
-	assert.True(t.T(), ok)
-	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	t.assertAudited(role.LOGIN_SUCCESS, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.Auth.UserID && event.Provider == string(role.CHULA_SSO)
+	})
 }
 
-func (t *AuthServiceTest) TestVerifyTicketGrpcErr() {
-	ticket := faker.Word()
+func (t *AuthServiceTest) TestVerifyGoogleLoginSuccessNotFirstTimeLogin() {
+	want := &auth_proto.VerifyGoogleLoginResponse{
+		Credential: t.Credential,
+	}
+
+	code := faker.Word()
+
+	t.UserDto.StudentID = "6430000021"
+	t.Auth.StudentID = t.UserDto.StudentID
 
 	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: role.GOOGLE}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	googleOauthClient := &mock.GoogleOauthClientMock{}
+	googleOauthClient.On("GetUserEmail", code, t.LoginState.CodeVerifier, t.LoginState.Nonce).Return(&client.GoogleUserEmailResponse{
+		Subject:   faker.UUIDDigit(),
+		Email:     t.UserDto.StudentID + "@student.chula.ac.th",
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+	}, nil)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
-	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(nil, status.Error(codes.Unavailable, "Cannot connect to chula sso"))
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider(role.GOOGLE), t.LoginState.State).Return(t.LoginState, nil)
 
 	userService := &mock.UserServiceMock{}
-	userService.On("FindByStudentID", t.UserDto.Id).Return(nil, t.NotFoundErr)
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
 
 	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
-	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
 
-	st, ok := status.FromError(err)
+	srv := NewService(repo, providers, tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: code, State: t.LoginState.State})
 
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestVerifyGoogleLoginNotChulaEmail() {
+	code := faker.Word()
+
+	googleOauthClient := &mock.GoogleOauthClientMock{}
+	googleOauthClient.On("GetUserEmail", code, t.LoginState.CodeVerifier, t.LoginState.Nonce).Return(&client.GoogleUserEmailResponse{Subject: faker.UUIDDigit(), Email: faker.Email()}, nil)
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider(role.GOOGLE), t.LoginState.State).Return(t.LoginState, nil)
+
+	userService := &mock.UserServiceMock{}
+
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
+
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: code, State: t.LoginState.State})
+
+	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
 	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.Unavailable, st.Code())
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	userService.AssertNotCalled(t.T(), "FindByStudentID", testify.Anything)
 }
 
-func (t *AuthServiceTest) TestValidateSuccess() {
-	want := &auth_proto.ValidateResponse{
-		UserId: t.UserDto.Id,
-		Role:   t.Auth.Role,
+func (t *AuthServiceTest) TestVerifyTicketLinkedIdentity() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
 	}
-	token := faker.Word()
+
+	ticket := faker.Word()
+	uid := faker.Word()
+
+	link := &auth.Identity{Provider: string(role.CHULA_SSO), Subject: uid, AuthID: t.Auth.ID}
 
 	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.CHULA_SSO), uid, &auth.Identity{}).Return(link, nil)
+	repo.On("FindByID", t.Auth.ID.String(), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
 	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{UID: uid, Ouid: "6430000021"}, nil)
 
 	userService := &mock.UserServiceMock{}
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
-
-	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, actual)
+	userService.AssertNotCalled(t.T(), "FindByStudentID", testify.Anything)
+	repo.AssertNumberOfCalls(t.T(), "CreateIdentity", 0)
 }
 
-func (t *AuthServiceTest) TestValidateInvalidToken() {
-	token := faker.Word()
+func (t *AuthServiceTest) TestVerifyGoogleLoginPendingEmailLink() {
+	want := &auth_proto.VerifyGoogleLoginResponse{
+		Credential: t.Credential,
+	}
+
+	code := faker.Word()
+	subject := faker.UUIDDigit()
+	email := faker.Email()
+
+	pending := &auth.Identity{Base: entity.Base{ID: uuid.New()}, Provider: string(role.GOOGLE), Email: email, AuthID: t.Auth.ID}
+	claimed := *pending
+	claimed.Subject = subject
 
 	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.GOOGLE), subject, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindPendingIdentityByEmail", string(role.GOOGLE), email, &auth.Identity{}).Return(pending, nil)
+	repo.On("UpdateIdentity", pending.ID.String(), &claimed).Return(&claimed, nil)
+	repo.On("FindByID", t.Auth.ID.String(), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: role.GOOGLE}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	googleOauthClient := &mock.GoogleOauthClientMock{}
+	googleOauthClient.On("GetUserEmail", code, t.LoginState.CodeVerifier, t.LoginState.Nonce).Return(&client.GoogleUserEmailResponse{Subject: subject, Email: email, EmailVerified: true}, nil)
 
-	userService := &mock.UserServiceMock{}
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider(role.GOOGLE), t.LoginState.State).Return(t.LoginState, nil)
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
 
-	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+	srv := NewService(repo, providers, tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: code, State: t.LoginState.State})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertNumberOfCalls(t.T(), "UpdateIdentity", 1)
+}
+
+func (t *AuthServiceTest) TestVerifyGoogleLoginPendingEmailLinkUnverified() {
+	code := faker.Word()
+	subject := faker.UUIDDigit()
+	email := faker.Email()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.GOOGLE), subject, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+
+	googleOauthClient := &mock.GoogleOauthClientMock{}
+	googleOauthClient.On("GetUserEmail", code, t.LoginState.CodeVerifier, t.LoginState.Nonce).Return(&client.GoogleUserEmailResponse{Subject: subject, Email: email}, nil)
+
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider(role.GOOGLE), t.LoginState.State).Return(t.LoginState, nil)
+
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
+
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: code, State: t.LoginState.State})
 
 	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	repo.AssertNotCalled(t.T(), "FindPendingIdentityByEmail", testify.Anything, testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestSendMagicLinkNotEnabled() {
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.SendMagicLink(context.Background(), &auth_proto.SendMagicLinkRequest{Email: faker.Email()})
 
+	st, ok := status.FromError(err)
 	assert.True(t.T(), ok)
 	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	assert.Equal(t.T(), codes.Unimplemented, st.Code())
 }
 
-func (t *AuthServiceTest) TestRedeemRefreshTokenSuccess() {
+func (t *AuthServiceTest) TestVerifyMagicLinkLinkedEmail() {
+	want := &auth_proto.VerifyMagicLinkResponse{
+		Credential: t.Credential,
+	}
+
 	token := faker.Word()
-	t.Auth.RefreshToken = utils.Hash([]byte(t.Credential.RefreshToken))
+	email := "john.d@alumni.chula.ac.th"
 
-	want := &auth_proto.RefreshTokenResponse{Credential: t.Credential}
+	link := &auth.Identity{Provider: string(role.EMAIL), Subject: email, Email: email, AuthID: t.Auth.ID}
 
 	repo := &mock.RepositoryMock{}
-	repo.On("FindByRefreshToken", utils.Hash([]byte(token)), &auth.Auth{}).Return(t.Auth, nil)
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	repo.On("FindIdentity", string(role.EMAIL), email, &auth.Identity{}).Return(link, nil)
+	repo.On("FindByID", t.Auth.ID.String(), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: role.EMAIL}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
-
-	userService := &mock.UserServiceMock{}
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", token).Return(&dto.MagicLink{Email: email}, nil)
 
 	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateRefreshToken").Return(token)
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	emailProvider, err := email_provider.NewProvider(magicLinkService, &mock.MailerMock{}, cfgldr.MagicLink{Url: "https://rabnongkaomai.com/login/email"})
+	assert.Nil(t.T(), err)
 
-	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+	providers := []provider.IdentityProvider{emailProvider}
+
+	srv := NewService(repo, providers, tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyMagicLink(context.Background(), &auth_proto.VerifyMagicLinkRequest{Token: token})
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want, actual)
 }
 
-func (t *AuthServiceTest) TestRedeemRefreshTokenInvalidToken() {
+func (t *AuthServiceTest) TestVerifyMagicLinkNotLinkedEmail() {
 	token := faker.Word()
-	t.Credential.RefreshToken = utils.Hash([]byte(token))
 
 	repo := &mock.RepositoryMock{}
-	repo.On("FindByRefreshToken", t.Credential.RefreshToken, &auth.Auth{}).Return(nil, errors.New("Not found token"))
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	t.unlinkedIdentity(repo)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
-
-	userService := &mock.UserServiceMock{}
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", token).Return(&dto.MagicLink{Email: "john.d@alumni.chula.ac.th"}, nil)
 
-	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateRefreshToken").Return(token)
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	emailProvider, err := email_provider.NewProvider(magicLinkService, &mock.MailerMock{}, cfgldr.MagicLink{Url: "https://rabnongkaomai.com/login/email"})
+	assert.Nil(t.T(), err)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	providers := []provider.IdentityProvider{emailProvider}
 
-	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyMagicLink(context.Background(), &auth_proto.VerifyMagicLinkRequest{Token: token})
 
 	st, ok := status.FromError(err)
-
 	assert.True(t.T(), ok)
 	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "CreateSession", 0)
 }
 
-func (t *AuthServiceTest) TestRedeemRefreshTokenInternalErr() {
+func (t *AuthServiceTest) TestVerifyMagicLinkNewStudent() {
 	token := faker.Word()
-	t.Credential.RefreshToken = utils.Hash([]byte(token))
+	t.UserDto.StudentID = "6430000021"
+	email := t.UserDto.StudentID + "@student.chula.ac.th"
 
 	repo := &mock.RepositoryMock{}
-	repo.On("FindByRefreshToken", t.Credential.RefreshToken, &auth.Auth{}).Return(t.Auth, nil)
+	t.unlinkedIdentity(repo)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	magicLinkService := &mock.MagicLinkServiceMock{}
+	magicLinkService.On("Consume", token).Return(&dto.MagicLink{Email: email}, nil)
 
 	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(nil, status.Error(codes.NotFound, "not found user"))
 
-	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateRefreshToken").Return(token)
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
+	emailProvider, err := email_provider.NewProvider(magicLinkService, &mock.MailerMock{}, cfgldr.MagicLink{Url: "https://rabnongkaomai.com/login/email"})
+	assert.Nil(t.T(), err)
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	providers := []provider.IdentityProvider{emailProvider}
 
-	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyMagicLink(context.Background(), &auth_proto.VerifyMagicLinkRequest{Token: token})
 
 	st, ok := status.FromError(err)
-
 	assert.True(t.T(), ok)
 	assert.Nil(t.T(), actual)
-	assert.Equal(t.T(), codes.Internal, st.Code())
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	userService.AssertCalled(t.T(), "FindByStudentID", t.UserDto.StudentID)
+	userService.AssertNotCalled(t.T(), "Create", testify.Anything)
+	repo.AssertNotCalled(t.T(), "Create", testify.Anything)
 }
 
-func (t *AuthServiceTest) TestCreateCredentialsSuccess() {
-	token := faker.Word()
-	t.Credential.RefreshToken = utils.Hash([]byte(faker.Word()))
+func (t *AuthServiceTest) TestVerifyGoogleLoginInvalidState() {
+	code := faker.Word()
 
-	want := t.Credential
+	googleOauthClient := &mock.GoogleOauthClientMock{}
 
-	repo := &mock.RepositoryMock{}
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider(role.GOOGLE), t.LoginState.State).Return(nil, state_svc.ErrInvalidState)
 
-	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
 
-	userService := &mock.UserServiceMock{}
+	srv := NewService(&mock.RepositoryMock{}, providers, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: code, State: t.LoginState.State})
 
-	tokenService := &mock.TokenServiceMock{}
-	tokenService.On("CreateRefreshToken").Return(token)
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(t.Credential, nil)
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	googleOauthClient.AssertNotCalled(t.T(), "GetUserEmail", testify.Anything, testify.Anything, testify.Anything)
+}
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+func (t *AuthServiceTest) TestGetGoogleLoginUrlSuccess() {
+	want := &auth_proto.GetGoogleLoginUrlResponse{
+		Url:   "https://accounts.google.com/o/oauth2/auth",
+		State: t.LoginState.State,
+	}
 
-	credentials, err := srv.CreateNewCredential(t.Auth)
+	googleOauthClient := &mock.GoogleOauthClientMock{}
+	googleOauthClient.On("AuthCodeURL", t.LoginState.State, t.LoginState.Nonce, utils.CodeChallenge(t.LoginState.CodeVerifier)).Return(want.Url)
 
-	assert.Nilf(t.T(), err, "error: %v", err)
-	assert.Equal(t.T(), want, credentials)
-}
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Create", role.Provider(role.GOOGLE)).Return(t.LoginState, nil)
 
-func (t *AuthServiceTest) TestCreateCredentialsInternalErr() {
-	want := errors.New("Invalid secret key")
+	providers := []provider.IdentityProvider{google.NewProvider(googleOauthClient)}
 
-	token := faker.Word()
-	t.Credential.RefreshToken = utils.Hash([]byte(faker.Word()))
+	srv := NewService(&mock.RepositoryMock{}, providers, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.GetGoogleLoginUrl(context.Background(), &auth_proto.GetGoogleLoginUrlRequest{})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestVerifyGoogleLoginProviderNotRegistered() {
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyGoogleLogin(context.Background(), &auth_proto.VerifyGoogleLoginRequest{Code: faker.Word()})
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unimplemented, st.Code())
+}
+
+func (t *AuthServiceTest) TestGetOidcLoginUrlSuccess() {
+	t.LoginState.Provider = "entra"
+
+	want := &auth_proto.GetOidcLoginUrlResponse{
+		Url:   "https://idp.example.com/authorize",
+		State: t.LoginState.State,
+	}
+
+	oidcProvider := &mock.RedirectProviderMock{}
+	oidcProvider.On("Name").Return(role.Provider("entra"))
+	oidcProvider.On("LoginUrl", t.LoginState).Return(want.Url)
+
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Create", role.Provider("entra")).Return(t.LoginState, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, []provider.IdentityProvider{oidcProvider}, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.GetOidcLoginUrl(context.Background(), &auth_proto.GetOidcLoginUrlRequest{Provider: "entra"})
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestGetOidcLoginUrlNotRedirectProvider() {
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.GetOidcLoginUrl(context.Background(), &auth_proto.GetOidcLoginUrlRequest{Provider: string(role.CHULA_SSO)})
+
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unimplemented, st.Code())
+}
+
+func (t *AuthServiceTest) TestVerifyOidcLoginSuccessNotFirstTimeLogin() {
+	want := &auth_proto.VerifyOidcLoginResponse{
+		Credential: t.Credential,
+	}
+
+	code := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: "entra"}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	oidcProvider := &mock.RedirectProviderMock{}
+	oidcProvider.On("Name").Return(role.Provider("entra"))
+	t.LoginState.Provider = "entra"
+
+	stateService := &mock.StateServiceMock{}
+	stateService.On("Consume", role.Provider("entra"), t.LoginState.State).Return(t.LoginState, nil)
+
+	oidcProvider.On("Resolve", code, t.LoginState).Return(&dto.Identity{
+		StudentID: t.UserDto.StudentID,
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+		Provider:  role.Provider("entra"),
+	}, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, []provider.IdentityProvider{oidcProvider}, tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, stateService, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyOidcLogin(context.Background(), &auth_proto.VerifyOidcLoginRequest{Provider: "entra", Code: code, State: t.LoginState.State})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketSyncIdentity() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
+	}
+
+	ticket := faker.Word()
+	outdated := *t.Auth
+	outdated.Year = "2"
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(&outdated, nil)
+	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{Ouid: t.UserDto.StudentID}, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertNumberOfCalls(t.T(), "Update", 1)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketInvalid() {
+	ticket := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(nil, status.Error(codes.Unauthenticated, "Cannot connect to chula sso"))
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.Id).Return(nil, t.NotFoundErr)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestVerifyForbiddenYear() {
+	ticket := faker.Word()
+
+	t.UserDto.StudentID = "60xxxxxx21"
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindRoleGrantByStudentID", t.UserDto.StudentID, &auth.RoleGrant{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSORes := &dto.ChulaSSOCredential{
+		UID:         faker.Word(),
+		Username:    faker.Username(),
+		Gecos:       faker.Username(),
+		Email:       faker.Email(),
+		Disable:     false,
+		Roles:       []string{"student"},
+		Firstname:   t.UserDto.Firstname,
+		Lastname:    t.UserDto.Lastname,
+		FirstnameTH: faker.FirstName(),
+		LastnameTH:  faker.LastName(),
+		Ouid:        t.UserDto.StudentID,
+	}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(chulaSSORes, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(nil, status.Error(codes.NotFound, "not found user"))
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.YEAR_NOT_ALLOWED))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), string(role.YEAR_NOT_ALLOWED), info.Reason)
+	t.assertAudited(role.LOGIN_FAILURE, func(event *audit_entity.AuditEvent) bool {
+		return event.StudentID == t.UserDto.StudentID && event.Detail == string(role.YEAR_NOT_ALLOWED)
+	})
+}
+
+func (t *AuthServiceTest) TestVerifyTicketExistingUserDenied() {
+	ticket := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+		Ouid:      t.UserDto.StudentID,
+	}, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.STUDENT_ID_DENIED))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), string(role.STUDENT_ID_DENIED), info.Reason)
+	repo.AssertNotCalled(t.T(), "CreateSession", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketLinkedStudentDenied() {
+	ticket := faker.Word()
+	t.Auth.StudentID = t.UserDto.StudentID
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.CHULA_SSO), t.UserDto.StudentID, &auth.Identity{}).Return(&auth.Identity{AuthID: t.Auth.ID}, nil)
+	repo.On("FindByID", t.Auth.ID.String(), &auth.Auth{}).Return(t.Auth, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Ouid: t.UserDto.StudentID,
+	}, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.OUTSIDE_LOGIN_WINDOW))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	repo.AssertNotCalled(t.T(), "CreateSession", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketExistingAdminSkipsEligibility() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
+	}
+
+	ticket := faker.Word()
+	t.Auth.Role = string(role.ADMIN)
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+	repo.On("FindByUserID", t.UserDto.Id, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Firstname: t.UserDto.Firstname,
+		Lastname:  t.UserDto.Lastname,
+		Ouid:      t.UserDto.StudentID,
+	}, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.StudentID).Return(t.UserDto, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.OUTSIDE_LOGIN_WINDOW))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	eligibilityService.AssertNotCalled(t.T(), "Check", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketLinkedStaffSkipsEligibility() {
+	want := &auth_proto.VerifyTicketResponse{
+		Credential: t.Credential,
+	}
+
+	ticket := faker.Word()
+	t.Auth.StudentID = t.UserDto.StudentID
+	t.Auth.Role = role.BAAN_STAFF
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.CHULA_SSO), t.UserDto.StudentID, &auth.Identity{}).Return(&auth.Identity{AuthID: t.Auth.ID}, nil)
+	repo.On("FindByID", t.Auth.ID.String(), &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(&dto.ChulaSSOCredential{
+		Ouid: t.UserDto.StudentID,
+	}, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	eligibilityService := &mock.EligibilityServiceMock{}
+	eligibilityService.On("Check", t.UserDto.StudentID).Return(t.eligibilityDenial(role.STUDENT_ID_DENIED))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	eligibilityService.AssertNotCalled(t.T(), "Check", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestVerifyTicketGrpcErr() {
+	ticket := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	t.unlinkedIdentity(repo)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+	chulaSSOClient.On("VerifyTicket", ticket, &dto.ChulaSSOCredential{}).Return(nil, status.Error(codes.Unavailable, "Cannot connect to chula sso"))
+
+	userService := &mock.UserServiceMock{}
+	userService.On("FindByStudentID", t.UserDto.Id).Return(nil, t.NotFoundErr)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	actual, err := srv.VerifyTicket(context.Background(), &auth_proto.VerifyTicketRequest{Ticket: ticket})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unavailable, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateSuccess() {
+	want := &auth_proto.ValidateResponse{
+		UserId:      t.UserDto.Id,
+		Role:        t.Auth.Role,
+		Permissions: []string{string(role.SESSION_MANAGE)},
+	}
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(t.UserCredential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("FindByRole", t.UserCredential.Role).Return([]string{string(role.SESSION_MANAGE)}, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, permissionService, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestValidateInvalidToken() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(nil, errors.New("Invalid token"))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestIntrospectActive() {
+	introspection := &dto.TokenIntrospection{
+		Active:    true,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		IssuedAt:  time.Now().Unix(),
+		Issuer:    faker.Word(),
+		Subject:   t.Auth.UserID,
+		Role:      t.Auth.Role,
+		SessionId: t.Session.ID.String(),
+		TokenType: role.BEARER,
+	}
+
+	want := &auth_proto.IntrospectResponse{
+		Active:    true,
+		Exp:       introspection.ExpiresAt,
+		Iat:       introspection.IssuedAt,
+		Iss:       introspection.Issuer,
+		Sub:       introspection.Subject,
+		Role:      introspection.Role,
+		SessionId: introspection.SessionId,
+		TokenType: role.BEARER,
+	}
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Introspect", token).Return(introspection, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectInactive() {
+	want := &auth_proto.IntrospectResponse{Active: false}
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Introspect", token).Return(&dto.TokenIntrospection{Active: false}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIntrospectInternalErr() {
+	token := faker.Word()
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Introspect", token).Return(nil, errors.New("Internal service error"))
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Introspect(context.Background(), &auth_proto.IntrospectRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, st.Code())
+}
+
+func (t *AuthServiceTest) adminCredential() *dto.UserCredential {
+	return &dto.UserCredential{
+		UserId:    faker.UUIDDigit(),
+		SessionId: uuid.New().String(),
+		Role:      role.ADMIN,
+	}
+}
+
+func (t *AuthServiceTest) TestGetUserRoleSuccess() {
+	want := &auth_proto.GetUserRoleResponse{
+		UserId: t.Auth.UserID,
+		Role:   t.Auth.Role,
+	}
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.GetUserRole(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.GetUserRoleRequest{Token: token, UserId: t.Auth.UserID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestSetUserRoleSuccess() {
+	want := &auth_proto.SetUserRoleResponse{
+		UserId: t.Auth.UserID,
+		Role:   role.BAAN_STAFF,
+	}
+	token := faker.Word()
+
+	promoted := *t.Auth
+	promoted.Role = role.BAAN_STAFF
+
+	var sessions []*auth.Session
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("Update", &promoted).Return(&promoted, nil)
+	repo.On("FindSessionsByUserID", t.Auth.UserID, &sessions).Return(t.Sessions, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("UpdateRole", t.Sessions[0].ID.String(), role.Role(role.BAAN_STAFF)).Return(nil)
+	tokenService.On("UpdateRole", t.Sessions[1].ID.String(), role.Role(role.BAAN_STAFF)).Return(nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.SetUserRole(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.SetUserRoleRequest{Token: token, UserId: t.Auth.UserID, Role: role.BAAN_STAFF})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	tokenService.AssertNumberOfCalls(t.T(), "UpdateRole", 2)
+	t.assertAudited(role.ROLE_CHANGE, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.Auth.UserID && event.ActorID != "" && event.Detail == t.Auth.Role+" -> "+role.BAAN_STAFF
+	})
+}
+
+func (t *AuthServiceTest) TestSetUserRoleInvalidRole() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.SetUserRole(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.SetUserRoleRequest{Token: token, UserId: t.Auth.UserID, Role: "superuser"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "Update", 0)
+}
+
+func (t *AuthServiceTest) TestSetUserRoleNotFound() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.SetUserRole(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.SetUserRoleRequest{Token: token, UserId: t.Auth.UserID, Role: string(role.ADMIN)})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+}
+
+func (t *AuthServiceTest) TestCheckPermissionAllowed() {
+	want := &auth_proto.CheckPermissionResponse{Allowed: true}
+	ctx := utils.NewCredentialContext(context.Background(), t.UserCredential)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("HasPermission", t.UserCredential.Role, string(role.SESSION_MANAGE)).Return(true, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, permissionService, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CheckPermission(ctx, &auth_proto.CheckPermissionRequest{Permission: string(role.SESSION_MANAGE)})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestCheckPermissionDenied() {
+	want := &auth_proto.CheckPermissionResponse{Allowed: false}
+	ctx := utils.NewCredentialContext(context.Background(), t.UserCredential)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("HasPermission", t.UserCredential.Role, string(role.ROLE_WRITE)).Return(false, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, permissionService, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CheckPermission(ctx, &auth_proto.CheckPermissionRequest{Permission: string(role.ROLE_WRITE)})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestCheckPermissionMissingPermission() {
+	ctx := utils.NewCredentialContext(context.Background(), t.UserCredential)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CheckPermission(ctx, &auth_proto.CheckPermissionRequest{})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *AuthServiceTest) TestImportRoleGrantsSuccess() {
+	want := &auth_proto.ImportRoleGrantsResponse{Imported: 2}
+	credential := t.adminCredential()
+	ctx := utils.NewCredentialContext(context.Background(), credential)
+
+	grants := []*auth.RoleGrant{
+		{StudentID: "6431234521", Role: role.BAAN_STAFF, GrantedBy: credential.UserId},
+		{StudentID: "6531234523", Role: role.EVENT_STAFF, GrantedBy: credential.UserId},
+	}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("UpsertRoleGrants", &grants).Return(nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ImportRoleGrants(ctx, &auth_proto.ImportRoleGrantsRequest{Csv: []byte("student_id,role\n6431234521,baan_staff\n6531234523,event_staff\n")})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestImportRoleGrantsInvalidCsv() {
+	ctx := utils.NewCredentialContext(context.Background(), t.adminCredential())
+
+	repo := &mock.RepositoryMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ImportRoleGrants(ctx, &auth_proto.ImportRoleGrantsRequest{Csv: []byte("6431234521,superuser\n")})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "UpsertRoleGrants", 0)
+}
+
+func (t *AuthServiceTest) TestLinkIdentitySuccess() {
+	admin := t.adminCredential()
+	email := faker.Email()
+
+	linked := &auth.Identity{
+		Base:     entity.Base{ID: uuid.New()},
+		Provider: string(role.GOOGLE),
+		Email:    email,
+		AuthID:   t.Auth.ID,
+		LinkedBy: admin.UserId,
+	}
+
+	want := &auth_proto.LinkIdentityResponse{Identity: RawToDtoIdentity(linked)}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("FindPendingIdentityByEmail", string(role.GOOGLE), email, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("CreateIdentity", &auth.Identity{Provider: string(role.GOOGLE), Email: email, AuthID: t.Auth.ID, LinkedBy: admin.UserId}).Return(linked, nil)
+
+	providers := []provider.IdentityProvider{google.NewProvider(&mock.GoogleOauthClientMock{})}
+
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.LinkIdentity(utils.NewCredentialContext(context.Background(), admin), &auth_proto.LinkIdentityRequest{UserId: t.Auth.UserID, Provider: role.GOOGLE, Email: email})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestLinkIdentityAlreadyLinked() {
+	subject := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("FindIdentity", string(role.CHULA_SSO), subject, &auth.Identity{}).Return(&auth.Identity{Subject: subject}, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.LinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.LinkIdentityRequest{UserId: t.Auth.UserID, Provider: string(role.CHULA_SSO), Subject: subject})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.AlreadyExists, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "CreateIdentity", 0)
+}
+
+func (t *AuthServiceTest) TestLinkIdentityInvalidProvider() {
+	repo := &mock.RepositoryMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.LinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.LinkIdentityRequest{UserId: t.Auth.UserID, Provider: "github", Email: faker.Email()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *AuthServiceTest) TestLinkIdentityNoSubjectOrEmail() {
+	repo := &mock.RepositoryMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.LinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.LinkIdentityRequest{UserId: t.Auth.UserID, Provider: string(role.CHULA_SSO)})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+}
+
+func (t *AuthServiceTest) TestLinkIdentityNotFoundUser() {
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(nil, gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.LinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.LinkIdentityRequest{UserId: t.Auth.UserID, Provider: string(role.CHULA_SSO), Subject: faker.Word()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+}
+
+func (t *AuthServiceTest) TestCreateAccountSuccess() {
+	admin := t.adminCredential()
+	email := "john.d@alumni.chula.ac.th"
+
+	created := &auth.Auth{Base: entity.Base{ID: uuid.New()}, Role: role.USER, UserID: t.UserDto.Id}
+	linked := &auth.Identity{
+		Base:     entity.Base{ID: uuid.New()},
+		Provider: string(role.GOOGLE),
+		Email:    email,
+		AuthID:   created.ID,
+		LinkedBy: admin.UserId,
+	}
+
+	want := &auth_proto.CreateAccountResponse{UserId: t.UserDto.Id, Identity: RawToDtoIdentity(linked)}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindPendingIdentityByEmail", string(role.GOOGLE), email, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("Create", &auth.Auth{Role: role.USER, UserID: t.UserDto.Id}).Return(created, nil)
+	repo.On("CreateIdentity", &auth.Identity{Provider: string(role.GOOGLE), Email: email, AuthID: created.ID, LinkedBy: admin.UserId}).Return(linked, nil)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("Create", &user_proto.User{Firstname: "John", Lastname: "Doe", Email: email}).Return(t.UserDto, nil)
+
+	providers := []provider.IdentityProvider{google.NewProvider(&mock.GoogleOauthClientMock{})}
+
+	srv := NewService(repo, providers, &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CreateAccount(utils.NewCredentialContext(context.Background(), admin), &auth_proto.CreateAccountRequest{Provider: role.GOOGLE, Email: email, Firstname: "John", Lastname: "Doe"})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	t.assertAudited(role.ACCOUNT_CREATE, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.UserDto.Id && event.ActorID == admin.UserId && event.StudentID == ""
+	})
+}
+
+func (t *AuthServiceTest) TestCreateAccountAlreadyLinked() {
+	subject := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.CHULA_SSO), subject, &auth.Identity{}).Return(&auth.Identity{Subject: subject}, nil)
+
+	userService := &mock.UserServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CreateAccount(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.CreateAccountRequest{Provider: string(role.CHULA_SSO), Subject: subject, Firstname: "John", Lastname: "Doe"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.AlreadyExists, st.Code())
+	userService.AssertNotCalled(t.T(), "Create", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestCreateAccountInvalidArgument() {
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+	ctx := utils.NewCredentialContext(context.Background(), t.adminCredential())
+
+	for _, req := range []*auth_proto.CreateAccountRequest{
+		{Provider: "github", Subject: faker.Word(), Firstname: "John", Lastname: "Doe"},
+		{Provider: string(role.CHULA_SSO), Firstname: "John", Lastname: "Doe"},
+		{Provider: string(role.CHULA_SSO), Subject: faker.Word()},
+	} {
+		actual, err := srv.CreateAccount(ctx, req)
+
+		st, ok := status.FromError(err)
+
+		assert.True(t.T(), ok)
+		assert.Nil(t.T(), actual)
+		assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	}
+}
+
+func (t *AuthServiceTest) TestCreateAccountUserServiceDown() {
+	subject := faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindIdentity", string(role.CHULA_SSO), subject, &auth.Identity{}).Return(nil, gorm.ErrRecordNotFound)
+
+	userService := &mock.UserServiceMock{}
+	userService.On("Create", &user_proto.User{Firstname: "John", Lastname: "Doe"}).Return(nil, t.ServiceDownErr)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.CreateAccount(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.CreateAccountRequest{Provider: string(role.CHULA_SSO), Subject: subject, Firstname: "John", Lastname: "Doe"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unavailable, st.Code())
+	repo.AssertNotCalled(t.T(), "Create", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestUnlinkIdentitySuccess() {
+	identity := &auth.Identity{Base: entity.Base{ID: uuid.New()}, Provider: string(role.GOOGLE), Subject: faker.UUIDDigit(), AuthID: t.Auth.ID}
+
+	var identities []*auth.Identity
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("FindIdentitiesByAuthID", t.Auth.ID.String(), &identities).Return([]*auth.Identity{identity}, nil)
+	repo.On("DeleteIdentity", identity.ID.String()).Return(nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.UnlinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.UnlinkIdentityRequest{UserId: t.Auth.UserID, IdentityId: identity.ID.String()})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), &auth_proto.UnlinkIdentityResponse{Success: true}, actual)
+	repo.AssertNumberOfCalls(t.T(), "DeleteIdentity", 1)
+}
+
+func (t *AuthServiceTest) TestUnlinkIdentityNotOwned() {
+	var identities []*auth.Identity
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("FindIdentitiesByAuthID", t.Auth.ID.String(), &identities).Return([]*auth.Identity{}, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.UnlinkIdentity(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.UnlinkIdentityRequest{UserId: t.Auth.UserID, IdentityId: uuid.New().String()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "DeleteIdentity", 0)
+}
+
+func (t *AuthServiceTest) TestListIdentitiesSuccess() {
+	identities := []*auth.Identity{
+		{Base: entity.Base{ID: uuid.New()}, Provider: string(role.CHULA_SSO), Subject: faker.Word(), AuthID: t.Auth.ID},
+		{Base: entity.Base{ID: uuid.New()}, Provider: string(role.GOOGLE), Email: faker.Email(), AuthID: t.Auth.ID},
+	}
+
+	want := &auth_proto.ListIdentitiesResponse{Identities: RawToDtoIdentities(identities)}
+
+	var result []*auth.Identity
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("FindIdentitiesByAuthID", t.Auth.ID.String(), &result).Return(identities, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListIdentities(context.Background(), &auth_proto.ListIdentitiesRequest{UserId: t.Auth.UserID})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) serviceClient(secret string) *auth.ServiceClient {
+	return &auth.ServiceClient{
+		Base:       entity.Base{ID: uuid.New()},
+		ClientID:   "checkin",
+		SecretHash: utils.Hash([]byte(secret)),
+		Scopes:     "user:read user:checkin",
+	}
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenSuccess() {
+	secret := faker.Password()
+
+	want := &auth_proto.IssueServiceTokenResponse{
+		AccessToken: faker.Word(),
+		TokenType:   role.BEARER,
+		ExpiresIn:   300,
+		Scope:       "user:read user:checkin",
+	}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindServiceClient", "checkin", &auth.ServiceClient{}).Return(t.serviceClient(secret), nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateServiceToken", "checkin", []string{"user:read", "user:checkin"}).Return(want, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{ClientId: "checkin", ClientSecret: secret})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenSubsetScope() {
+	secret := faker.Password()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindServiceClient", "checkin", &auth.ServiceClient{}).Return(t.serviceClient(secret), nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateServiceToken", "checkin", []string{"user:checkin"}).Return(&auth_proto.IssueServiceTokenResponse{Scope: "user:checkin"}, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{ClientId: "checkin", ClientSecret: secret, Scope: "user:checkin"})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), "user:checkin", actual.Scope)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenInvalidScope() {
+	secret := faker.Password()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindServiceClient", "checkin", &auth.ServiceClient{}).Return(t.serviceClient(secret), nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{ClientId: "checkin", ClientSecret: secret, Scope: "user:checkin role:write"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	tokenService.AssertNotCalled(t.T(), "CreateServiceToken", testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenInvalidSecret() {
+	repo := &mock.RepositoryMock{}
+	repo.On("FindServiceClient", "checkin", &auth.ServiceClient{}).Return(t.serviceClient(faker.Password()), nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{ClientId: "checkin", ClientSecret: "wrong-secret"})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	tokenService.AssertNotCalled(t.T(), "CreateServiceToken", testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestIssueServiceTokenUnknownClient() {
+	repo := &mock.RepositoryMock{}
+	repo.On("FindServiceClient", "checkin", &auth.ServiceClient{}).Return(nil, gorm.ErrRecordNotFound)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.IssueServiceToken(context.Background(), &auth_proto.IssueServiceTokenRequest{ClientId: "checkin", ClientSecret: faker.Password()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateServiceToken() {
+	token := faker.Word()
+
+	want := &auth_proto.ValidateResponse{
+		ClientId:    "checkin",
+		Permissions: []string{"user:read"},
+	}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(&dto.UserCredential{ClientId: "checkin", Scopes: []string{"user:read"}}, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, permissionService, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	permissionService.AssertNotCalled(t.T(), "FindByRole", testify.Anything)
+}
+
+func (t *AuthServiceTest) impersonationCredential() *dto.UserCredential {
+	return &dto.UserCredential{
+		UserId:    t.Auth.UserID,
+		SessionId: uuid.New().String(),
+		Role:      role.Role(t.Auth.Role),
+		Actor:     faker.UUIDDigit(),
+	}
+}
+
+func (t *AuthServiceTest) TestImpersonateSuccess() {
+	admin := t.adminCredential()
+	credential := &auth_proto.Credential{AccessToken: faker.Word(), ExpiresIn: 900}
+
+	want := &auth_proto.ImpersonateResponse{Credential: credential}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateImpersonationCredentials", t.Auth, admin.UserId).Return(credential, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	reason := faker.Sentence()
+
+	actual, err := srv.Impersonate(utils.NewCredentialContext(context.Background(), admin), &auth_proto.ImpersonateRequest{UserId: t.Auth.UserID, Reason: reason})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	assert.Empty(t.T(), actual.Credential.RefreshToken)
+	t.assertAudited(role.IMPERSONATION_START, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.Auth.UserID && event.ActorID == admin.UserId && event.Detail == reason
+	})
+}
+
+func (t *AuthServiceTest) TestImpersonateNoReason() {
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Impersonate(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.ImpersonateRequest{UserId: t.Auth.UserID, Reason: " "})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	tokenService.AssertNotCalled(t.T(), "CreateImpersonationCredentials", testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestImpersonateAdmin() {
+	admin := *t.Auth
+	admin.Role = string(role.ADMIN)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(&admin, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Impersonate(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.ImpersonateRequest{UserId: t.Auth.UserID, Reason: faker.Sentence()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	tokenService.AssertNotCalled(t.T(), "CreateImpersonationCredentials", testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestImpersonateWhileImpersonating() {
+	credential := t.adminCredential()
+	credential.Actor = faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Impersonate(utils.NewCredentialContext(context.Background(), credential), &auth_proto.ImpersonateRequest{UserId: t.Auth.UserID, Reason: faker.Sentence()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+	repo.AssertNotCalled(t.T(), "FindByUserID", testify.Anything, testify.Anything)
+}
+
+func (t *AuthServiceTest) TestImpersonateNotFound() {
+	repo := &mock.RepositoryMock{}
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(nil, errors.New("Not found user"))
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Impersonate(utils.NewCredentialContext(context.Background(), t.adminCredential()), &auth_proto.ImpersonateRequest{UserId: t.Auth.UserID, Reason: faker.Sentence()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+}
+
+func (t *AuthServiceTest) TestValidateImpersonationToken() {
+	token := faker.Word()
+	credential := t.impersonationCredential()
+
+	want := &auth_proto.ValidateResponse{
+		UserId:      credential.UserId,
+		Role:        string(credential.Role),
+		Permissions: []string{string(role.SESSION_MANAGE)},
+		Actor:       credential.Actor,
+	}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("Validate", token).Return(credential, nil)
+
+	permissionService := &mock.PermissionServiceMock{}
+	permissionService.On("FindByRole", credential.Role).Return([]string{string(role.SESSION_MANAGE)}, nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, permissionService, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Validate(context.Background(), &auth_proto.ValidateRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+// assertAudited checks that an event of the kind was recorded and matches
+func (t *AuthServiceTest) assertAudited(kind role.AuditEvent, match func(event *audit_entity.AuditEvent) bool) {
+	t.auditService.AssertCalled(t.T(), "Record", testify.Anything, testify.MatchedBy(func(event *audit_entity.AuditEvent) bool {
+		return event.Event == string(kind) && match(event)
+	}))
+}
+
+func (t *AuthServiceTest) TestListAuditEventsSuccess() {
+	events := []*audit_entity.AuditEvent{
+		{Base: entity.Base{ID: uuid.New(), CreatedAt: time.Now()}, Event: string(role.LOGIN_SUCCESS), UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO), ClientIP: faker.IPv4()},
+		{Base: entity.Base{ID: uuid.New(), CreatedAt: time.Now()}, Event: string(role.LOGOUT), UserID: t.Auth.UserID},
+	}
+
+	since := time.Now().Add(-time.Hour).Unix()
+
+	want := &auth_proto.ListAuditEventsResponse{
+		Events:   RawToDtoAuditEvents(events),
+		Total:    42,
+		Page:     2,
+		PageSize: 2,
+	}
+
+	auditService := &mock.AuditServiceMock{}
+	auditService.On("Find", &dto.AuditFilter{UserID: t.Auth.UserID, Since: time.Unix(since, 0), Page: 2, PageSize: 2}).Return(events, int64(42), nil)
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListAuditEvents(context.Background(), &auth_proto.ListAuditEventsRequest{UserId: t.Auth.UserID, Since: since, Page: 2, PageSize: 2})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestListAuditEventsInvalidRange() {
+	auditService := &mock.AuditServiceMock{}
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListAuditEvents(context.Background(), &auth_proto.ListAuditEventsRequest{Since: time.Now().Unix(), Until: time.Now().Add(-time.Hour).Unix()})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.InvalidArgument, st.Code())
+	auditService.AssertNotCalled(t.T(), "Find", testify.Anything)
+}
+
+func (t *AuthServiceTest) TestListAuditEventsInternalErr() {
+	auditService := &mock.AuditServiceMock{}
+	auditService.On("Find", &dto.AuditFilter{}).Return(nil, int64(0), errors.New("connection refused"))
+
+	srv := NewService(&mock.RepositoryMock{}, t.newProviders(&mock.ChulaSSOClientMock{}), &mock.TokenServiceMock{}, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListAuditEvents(context.Background(), &auth_proto.ListAuditEventsRequest{})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, st.Code())
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenSuccess() {
+	token := faker.Word()
+
+	want := &auth_proto.RefreshTokenResponse{Credential: t.Credential}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("RotateRefreshToken", t.RefreshToken.ID.String()).Return(nil)
+	repo.On("UpdateSession", t.refreshedSession()).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(&t.RefreshToken.ID)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.refreshedSession(), t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertCalled(t.T(), "RotateRefreshToken", t.RefreshToken.ID.String())
+	repo.AssertNumberOfCalls(t.T(), "CreateRefreshToken", 1)
+	t.assertAudited(role.TOKEN_REFRESH, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.Auth.UserID
+	})
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenLegacySession() {
+	token := faker.Word()
+	t.Session.RefreshExpiresAt = time.Time{}
+	t.Session.ExpiresAt = time.Time{}
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("RotateRefreshToken", t.RefreshToken.ID.String()).Return(nil)
+	repo.On("UpdateSession", t.refreshedSession()).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(&t.RefreshToken.ID)).Return(t.RefreshToken, nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.refreshedSession(), t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential, actual.Credential)
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenInvalidToken() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(nil, errors.New("Not found token"))
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenExpired() {
+	testRedeemRefreshTokenExpired(t, func(session *auth.Session) {
+		session.RefreshExpiresAt = time.Now().Add(-time.Minute)
+	})
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenSessionExpired() {
+	testRedeemRefreshTokenExpired(t, func(session *auth.Session) {
+		session.ExpiresAt = time.Now().Add(-time.Minute)
+	})
+}
+
+func testRedeemRefreshTokenExpired(t *AuthServiceTest, expire func(session *auth.Session)) {
+	token := faker.Word()
+	expire(t.Session)
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "RotateRefreshToken", 0)
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenReused() {
+	token := faker.Word()
+	rotatedAt := time.Now()
+	t.RefreshToken.RotatedAt = &rotatedAt
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+	repo.On("DeleteRefreshTokensBySessionID", t.Session.ID.String()).Return(nil)
+	repo.On("DeleteSession", t.Session.ID.String()).Return(nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", t.Session.ID.String()).Return(nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.Session.ID.String())
+	repo.AssertCalled(t.T(), "DeleteRefreshTokensBySessionID", t.Session.ID.String())
+	repo.AssertCalled(t.T(), "DeleteSession", t.Session.ID.String())
+	tokenService.AssertNumberOfCalls(t.T(), "CreateCredentials", 0)
+	t.assertAudited(role.REFRESH_REUSE, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.Session.UserID
+	})
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenConcurrentRotation() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("RotateRefreshToken", t.RefreshToken.ID.String()).Return(gorm.ErrRecordNotFound)
+	repo.On("DeleteRefreshTokensBySessionID", t.Session.ID.String()).Return(nil)
+	repo.On("DeleteSession", t.Session.ID.String()).Return(nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", t.Session.ID.String()).Return(nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+	repo.AssertCalled(t.T(), "DeleteSession", t.Session.ID.String())
+}
+
+func (t *AuthServiceTest) TestRedeemRefreshTokenInternalErr() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindRefreshToken", utils.Hash([]byte(token)), &auth.RefreshToken{}).Return(t.RefreshToken, nil)
+	repo.On("FindSessionByID", t.Session.ID.String(), &auth.Session{}).Return(t.Session, nil)
+	repo.On("FindByUserID", t.Auth.UserID, &auth.Auth{}).Return(t.Auth, nil)
+	repo.On("RotateRefreshToken", t.RefreshToken.ID.String()).Return(nil)
+	repo.On("UpdateSession", t.refreshedSession()).Return(t.Session, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.refreshedSession(), t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RefreshToken(context.Background(), &auth_proto.RefreshTokenRequest{RefreshToken: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, st.Code())
+}
+
+func (t *AuthServiceTest) TestCreateCredentialsSuccess() {
+	token := faker.Word()
+	t.Credential.RefreshToken = utils.Hash([]byte(faker.Word()))
+
+	want := t.Credential
 
 	repo := &mock.RepositoryMock{}
-	repo.On("Update", t.Auth).Return(t.Auth, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateRefreshToken").Return(token)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	credentials, err := srv.CreateNewCredential(t.Auth, t.Session, nil)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, credentials)
+}
+
+func (t *AuthServiceTest) TestCreateCredentialsInternalErr() {
+	want := errors.New("Invalid secret key")
+
+	token := faker.Word()
+	t.Credential.RefreshToken = utils.Hash([]byte(faker.Word()))
+
+	repo := &mock.RepositoryMock{}
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
 
 	chulaSSOClient := &mock.ChulaSSOClientMock{}
 
@@ -465,12 +2231,376 @@ func (t *AuthServiceTest) TestCreateCredentialsInternalErr() {
 
 	tokenService := &mock.TokenServiceMock{}
 	tokenService.On("CreateRefreshToken").Return(token)
-	tokenService.On("CreateCredentials", t.Auth, t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(nil, errors.New("Invalid secret key"))
 
-	srv := NewService(repo, chulaSSOClient, tokenService, userService, t.conf, &t.oauthConf, t.googleOauthClient)
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
 
-	credentials, err := srv.CreateNewCredential(t.Auth)
+	credentials, err := srv.CreateNewCredential(t.Auth, t.Session, nil)
 
 	assert.Nil(t.T(), credentials)
 	assert.Equal(t.T(), want.Error(), err.Error())
 }
+
+func (t *AuthServiceTest) TestCreateNewSessionEvictOldestSession() {
+	t.conf.MaxSessions = 2
+
+	oldest := &auth.Session{Base: entity.Base{ID: uuid.New()}, UserID: t.Auth.UserID}
+	latest := &auth.Session{Base: entity.Base{ID: uuid.New()}, UserID: t.Auth.UserID}
+
+	var sessions []*auth.Session
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionsByUserID", t.Auth.UserID, &sessions).Return([]*auth.Session{oldest, latest}, nil)
+	repo.On("DeleteRefreshTokensBySessionID", oldest.ID.String()).Return(nil)
+	repo.On("DeleteSession", oldest.ID.String()).Return(nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", oldest.ID.String()).Return(nil)
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	credentials, err := srv.CreateNewSession(context.Background(), t.Auth, role.CHULA_SSO)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential, credentials)
+	repo.AssertCalled(t.T(), "DeleteSession", oldest.ID.String())
+	repo.AssertNotCalled(t.T(), "DeleteSession", latest.ID.String())
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", oldest.ID.String())
+}
+
+func (t *AuthServiceTest) TestCreateNewSessionUnderLimit() {
+	t.conf.MaxSessions = 2
+
+	var sessions []*auth.Session
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionsByUserID", t.Auth.UserID, &sessions).Return([]*auth.Session{{Base: entity.Base{ID: uuid.New()}}}, nil)
+	repo.On("CreateSession", &auth.Session{UserID: t.Auth.UserID, Provider: string(role.CHULA_SSO)}).Return(t.Session, nil)
+	repo.On("UpdateSession", t.Session).Return(t.Session, nil)
+	repo.On("CreateRefreshToken", t.newRefreshToken(nil)).Return(t.RefreshToken, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("CreateCredentials", t.Auth, t.Session, t.conf.Secret).Return(t.Credential, nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	credentials, err := srv.CreateNewSession(context.Background(), t.Auth, role.CHULA_SSO)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential, credentials)
+	repo.AssertNumberOfCalls(t.T(), "DeleteSession", 0)
+	tokenService.AssertNumberOfCalls(t.T(), "RemoveCredentials", 0)
+}
+
+func (t *AuthServiceTest) TestLogoutSuccess() {
+	want := &auth_proto.LogoutResponse{Success: true}
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("DeleteRefreshTokensBySessionID", t.UserCredential.SessionId).Return(nil)
+	repo.On("DeleteSession", t.UserCredential.SessionId).Return(nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", t.UserCredential.SessionId).Return(nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Logout(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.LogoutRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertCalled(t.T(), "DeleteSession", t.UserCredential.SessionId)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", t.UserCredential.SessionId)
+	t.assertAudited(role.LOGOUT, func(event *audit_entity.AuditEvent) bool {
+		return event.UserID == t.UserCredential.UserId && event.ActorID == ""
+	})
+}
+
+func (t *AuthServiceTest) TestLogoutImpersonation() {
+	want := &auth_proto.LogoutResponse{Success: true}
+	credential := t.impersonationCredential()
+
+	repo := &mock.RepositoryMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", credential.SessionId).Return(nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Logout(utils.NewCredentialContext(context.Background(), credential), &auth_proto.LogoutRequest{})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertNotCalled(t.T(), "DeleteSession", testify.Anything)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", credential.SessionId)
+}
+
+func (t *AuthServiceTest) TestLogoutMissingCredential() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Logout(context.Background(), &auth_proto.LogoutRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestLogoutInternalErr() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", t.UserCredential.SessionId).Return(errors.New("Internal service error"))
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.Logout(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.LogoutRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Internal, st.Code())
+	repo.AssertNotCalled(t.T(), "DeleteSession", t.UserCredential.SessionId)
+}
+
+func (t *AuthServiceTest) TestListSessionsSuccess() {
+	want := &auth_proto.ListSessionsResponse{
+		Sessions: []*auth_proto.Session{
+			{
+				Id:        t.Sessions[0].ID.String(),
+				Provider:  t.Sessions[0].Provider,
+				CreatedAt: t.Sessions[0].CreatedAt.Unix(),
+				IpAddress: t.Sessions[0].IPAddress,
+				UserAgent: t.Sessions[0].UserAgent,
+				Current:   true,
+				ExpiresAt: t.Sessions[0].ExpiresAt.Unix(),
+			},
+			{
+				Id:              t.Sessions[1].ID.String(),
+				Provider:        t.Sessions[1].Provider,
+				CreatedAt:       t.Sessions[1].CreatedAt.Unix(),
+				LastRefreshedAt: t.Sessions[1].LastRefreshedAt.Unix(),
+				IpAddress:       t.Sessions[1].IPAddress,
+				UserAgent:       t.Sessions[1].UserAgent,
+				Current:         false,
+				ExpiresAt:       t.Sessions[1].ExpiresAt.Unix(),
+			},
+		},
+	}
+	token := faker.Word()
+
+	var sessions []*auth.Session
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionsByUserID", t.UserCredential.UserId, &sessions).Return(t.Sessions, nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListSessions(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.ListSessionsRequest{Token: token})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *AuthServiceTest) TestListSessionsDeletesExpired() {
+	expired := *t.Sessions[1]
+	expired.RefreshExpiresAt = time.Now().Add(-time.Minute)
+
+	var sessions []*auth.Session
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionsByUserID", t.UserCredential.UserId, &sessions).Return([]*auth.Session{t.Sessions[0], &expired}, nil)
+	repo.On("DeleteRefreshTokensBySessionID", expired.ID.String()).Return(nil)
+	repo.On("DeleteSession", expired.ID.String()).Return(nil)
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", expired.ID.String()).Return(nil)
+
+	srv := NewService(repo, t.newProviders(&mock.ChulaSSOClientMock{}), tokenService, &mock.UserServiceMock{}, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListSessions(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.ListSessionsRequest{})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Len(t.T(), actual.Sessions, 1)
+	assert.Equal(t.T(), t.Sessions[0].ID.String(), actual.Sessions[0].Id)
+	repo.AssertCalled(t.T(), "DeleteSession", expired.ID.String())
+}
+
+func (t *AuthServiceTest) TestListSessionsMissingCredential() {
+	token := faker.Word()
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.ListSessions(context.Background(), &auth_proto.ListSessionsRequest{Token: token})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.Unauthenticated, st.Code())
+}
+
+func (t *AuthServiceTest) TestRevokeSessionSuccess() {
+	want := &auth_proto.RevokeSessionResponse{Success: true}
+	token := faker.Word()
+	sessionId := t.Sessions[1].ID.String()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionByID", sessionId, &auth.Session{}).Return(t.Sessions[1], nil)
+	repo.On("DeleteRefreshTokensBySessionID", sessionId).Return(nil)
+	repo.On("DeleteSession", sessionId).Return(nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("RemoveCredentials", sessionId).Return(nil)
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RevokeSession(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.RevokeSessionRequest{Token: token, SessionId: sessionId})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	repo.AssertCalled(t.T(), "DeleteSession", sessionId)
+	tokenService.AssertCalled(t.T(), "RemoveCredentials", sessionId)
+}
+
+func (t *AuthServiceTest) TestRevokeSessionNotOwner() {
+	token := faker.Word()
+	sessionId := t.Sessions[1].ID.String()
+	t.Sessions[1].UserID = faker.UUIDDigit()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionByID", sessionId, &auth.Session{}).Return(t.Sessions[1], nil)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RevokeSession(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.RevokeSessionRequest{Token: token, SessionId: sessionId})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+	repo.AssertNumberOfCalls(t.T(), "DeleteSession", 0)
+}
+
+func (t *AuthServiceTest) TestRevokeSessionNotFound() {
+	token := faker.Word()
+	sessionId := uuid.New().String()
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindSessionByID", sessionId, &auth.Session{}).Return(nil, gorm.ErrRecordNotFound)
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.RevokeSession(utils.NewCredentialContext(context.Background(), t.UserCredential), &auth_proto.RevokeSessionRequest{Token: token, SessionId: sessionId})
+
+	st, ok := status.FromError(err)
+
+	assert.True(t.T(), ok)
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), codes.NotFound, st.Code())
+}
+
+func (t *AuthServiceTest) TestGetJwksSuccess() {
+	jwk := &dto.Jwk{
+		Kty: "RSA",
+		Kid: faker.Word(),
+		Use: "sig",
+		Alg: "RS256",
+		N:   faker.Word(),
+		E:   "AQAB",
+	}
+
+	want := &auth_proto.GetJwksResponse{
+		Keys: []*auth_proto.Jwk{
+			{
+				Kty: jwk.Kty,
+				Kid: jwk.Kid,
+				Use: jwk.Use,
+				Alg: jwk.Alg,
+				N:   jwk.N,
+				E:   jwk.E,
+			},
+		},
+	}
+
+	repo := &mock.RepositoryMock{}
+
+	chulaSSOClient := &mock.ChulaSSOClientMock{}
+
+	userService := &mock.UserServiceMock{}
+
+	tokenService := &mock.TokenServiceMock{}
+	tokenService.On("GetJwks").Return(&dto.Jwks{Keys: []*dto.Jwk{jwk}})
+
+	srv := NewService(repo, t.newProviders(chulaSSOClient), tokenService, userService, &mock.PermissionServiceMock{}, t.eligibilityService, &mock.StateServiceMock{}, t.auditService, t.clientInfo, t.academicYear, t.conf)
+
+	actual, err := srv.GetJwks(context.Background(), &auth_proto.GetJwksRequest{})
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
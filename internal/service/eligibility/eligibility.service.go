@@ -0,0 +1,158 @@
+package eligibility
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	"github.com/isd-sgcu/rpkm66-auth/constant/utils"
+	_utils "github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type window struct {
+	startAt time.Time
+	endAt   time.Time
+}
+
+type serviceImpl struct {
+	years             map[int]bool
+	allowedFaculties  map[string]bool
+	deniedFaculties   map[string]bool
+	allowedStudentIDs map[string]bool
+	deniedStudentIDs  map[string]bool
+	windows           []window
+	academicYear      *_utils.AcademicYear
+	now               func() time.Time
+}
+
+func NewService(conf cfgldr.Eligibility, academicYear *_utils.AcademicYear) (*serviceImpl, error) {
+	s := &serviceImpl{
+		years:             map[int]bool{},
+		allowedFaculties:  map[string]bool{},
+		deniedFaculties:   map[string]bool{},
+		allowedStudentIDs: toSet(conf.AllowedStudentIDs),
+		deniedStudentIDs:  toSet(conf.DeniedStudentIDs),
+		academicYear:      academicYear,
+		now:               time.Now,
+	}
+
+	for _, year := range conf.Years {
+		s.years[year] = true
+	}
+
+	for _, code := range append(append([]string{}, conf.AllowedFaculties...), conf.DeniedFaculties...) {
+		if _, ok := utils.Faculties[code]; !ok {
+			return nil, errors.New(fmt.Sprintf("unknown faculty code %v", code))
+		}
+	}
+
+	s.allowedFaculties = toSet(conf.AllowedFaculties)
+	s.deniedFaculties = toSet(conf.DeniedFaculties)
+
+	for _, w := range conf.Windows {
+		startAt, err := time.Parse(time.RFC3339, w.StartAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while parsing the window start_at")
+		}
+
+		endAt, err := time.Parse(time.RFC3339, w.EndAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while parsing the window end_at")
+		}
+
+		if !endAt.After(startAt) {
+			return nil, errors.New("window end_at must be after start_at")
+		}
+
+		s.windows = append(s.windows, window{startAt: startAt, endAt: endAt})
+	}
+
+	return s, nil
+}
+
+// Check evaluates the rules in order, the first rule that fails decides the reason returned in the status details
+func (s *serviceImpl) Check(studentID string) error {
+	if s.deniedStudentIDs[studentID] {
+		return deny(auth.STUDENT_ID_DENIED, "Student id is not allowed to login", studentID)
+	}
+
+	if s.allowedStudentIDs[studentID] {
+		return nil
+	}
+
+	if !s.isInWindow() {
+		return deny(auth.OUTSIDE_LOGIN_WINDOW, "Login is not open", studentID)
+	}
+
+	// the same parsing as the account creation, a malformed id is denied here instead of failing later as an internal error
+	year, err := s.academicYear.CalYearFromID(studentID)
+	if err != nil || !_utils.IsStudentID(studentID) {
+		return deny(auth.INVALID_STUDENT_ID, "Invalid student id", studentID)
+	}
+
+	if _, err := _utils.GetFacultyFromID(studentID); err != nil {
+		return deny(auth.INVALID_STUDENT_ID, "Invalid student id", studentID)
+	}
+
+	faculty := studentID[8:10]
+
+	if s.deniedFaculties[faculty] {
+		return deny(auth.FACULTY_DENIED, "Forbidden faculty", studentID)
+	}
+
+	if len(s.allowedFaculties) > 0 && !s.allowedFaculties[faculty] {
+		return deny(auth.FACULTY_NOT_ALLOWED, "Forbidden faculty", studentID)
+	}
+
+	if len(s.years) > 0 {
+		yearInt, err := strconv.Atoi(year)
+		if err != nil || !s.years[yearInt] {
+			return deny(auth.YEAR_NOT_ALLOWED, "Forbidden study year", studentID)
+		}
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) isInWindow() bool {
+	if len(s.windows) == 0 {
+		return true
+	}
+
+	now := s.now()
+	for _, w := range s.windows {
+		if !now.Before(w.startAt) && now.Before(w.endAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func deny(reason auth.DenialReason, message string, studentID string) error {
+	st, err := status.New(codes.PermissionDenied, message).WithDetails(&errdetails.ErrorInfo{
+		Reason:   string(reason),
+		Domain:   auth.ELIGIBILITY_DOMAIN,
+		Metadata: map[string]string{"student_id": studentID},
+	})
+	if err != nil {
+		return status.Error(codes.PermissionDenied, message)
+	}
+
+	return st.Err()
+}
+
+func toSet(in []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range in {
+		set[v] = true
+	}
+
+	return set
+}
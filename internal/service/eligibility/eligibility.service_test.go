@@ -0,0 +1,147 @@
+package eligibility
+
+import (
+	"testing"
+	"time"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type EligibilityServiceTest struct {
+	suite.Suite
+	conf         cfgldr.Eligibility
+	academicYear *utils.AcademicYear
+}
+
+func TestEligibilityService(t *testing.T) {
+	suite.Run(t, new(EligibilityServiceTest))
+}
+
+func (t *EligibilityServiceTest) SetupTest() {
+	t.academicYear, _ = utils.NewAcademicYear("", 2566, nil)
+
+	t.conf = cfgldr.Eligibility{
+		Years:            []int{1, 2, 3},
+		DeniedFaculties:  []string{"20"},
+		DeniedStudentIDs: []string{"6631234521"},
+		Windows: []cfgldr.EligibilityWindow{
+			{StartAt: "2023-07-01T00:00:00+07:00", EndAt: "2023-08-01T00:00:00+07:00"},
+		},
+	}
+}
+
+func (t *EligibilityServiceTest) newService(conf cfgldr.Eligibility) *serviceImpl {
+	srv, err := NewService(conf, t.academicYear)
+	assert.Nil(t.T(), err)
+
+	srv.now = func() time.Time {
+		return time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC)
+	}
+
+	return srv
+}
+
+func (t *EligibilityServiceTest) assertDenied(err error, reason auth.DenialReason) {
+	st, ok := status.FromError(err)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), codes.PermissionDenied, st.Code())
+
+	details := st.Details()
+	assert.Len(t.T(), details, 1)
+
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	assert.True(t.T(), ok)
+	assert.Equal(t.T(), string(reason), info.Reason)
+	assert.Equal(t.T(), auth.ELIGIBILITY_DOMAIN, info.Domain)
+}
+
+func (t *EligibilityServiceTest) TestCheckSuccess() {
+	srv := t.newService(t.conf)
+
+	assert.Nil(t.T(), srv.Check("6630000021"))
+}
+
+func (t *EligibilityServiceTest) TestCheckStudentIDDenied() {
+	srv := t.newService(t.conf)
+
+	t.assertDenied(srv.Check("6631234521"), auth.STUDENT_ID_DENIED)
+}
+
+func (t *EligibilityServiceTest) TestCheckAllowlistBypassesRules() {
+	t.conf.AllowedStudentIDs = []string{"6030000020"}
+	srv := t.newService(t.conf)
+	srv.now = func() time.Time {
+		return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	assert.Nil(t.T(), srv.Check("6030000020"))
+}
+
+func (t *EligibilityServiceTest) TestCheckOutsideWindow() {
+	srv := t.newService(t.conf)
+	srv.now = func() time.Time {
+		return time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	t.assertDenied(srv.Check("6630000021"), auth.OUTSIDE_LOGIN_WINDOW)
+}
+
+func (t *EligibilityServiceTest) TestCheckInvalidStudentID() {
+	srv := t.newService(t.conf)
+
+	t.assertDenied(srv.Check("66300"), auth.INVALID_STUDENT_ID)
+	t.assertDenied(srv.Check("66xxxxxx21"), auth.INVALID_STUDENT_ID)
+	t.assertDenied(srv.Check("6630000000"), auth.INVALID_STUDENT_ID)
+
+	srv = t.newService(cfgldr.Eligibility{})
+
+	t.assertDenied(srv.Check("6a30000021"), auth.INVALID_STUDENT_ID)
+}
+
+func (t *EligibilityServiceTest) TestCheckFacultyDenied() {
+	srv := t.newService(t.conf)
+
+	t.assertDenied(srv.Check("6630000020"), auth.FACULTY_DENIED)
+}
+
+func (t *EligibilityServiceTest) TestCheckFacultyNotAllowed() {
+	t.conf.AllowedFaculties = []string{"23"}
+	srv := t.newService(t.conf)
+
+	t.assertDenied(srv.Check("6630000021"), auth.FACULTY_NOT_ALLOWED)
+}
+
+func (t *EligibilityServiceTest) TestCheckYearNotAllowed() {
+	srv := t.newService(t.conf)
+
+	t.assertDenied(srv.Check("6030000021"), auth.YEAR_NOT_ALLOWED)
+}
+
+func (t *EligibilityServiceTest) TestCheckEmptyPolicy() {
+	srv := t.newService(cfgldr.Eligibility{})
+
+	assert.Nil(t.T(), srv.Check("6030000021"))
+}
+
+func (t *EligibilityServiceTest) TestNewServiceUnknownFaculty() {
+	t.conf.AllowedFaculties = []string{"00"}
+
+	_, err := NewService(t.conf, t.academicYear)
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *EligibilityServiceTest) TestNewServiceInvalidWindow() {
+	t.conf.Windows = []cfgldr.EligibilityWindow{{StartAt: "2023-08-01T00:00:00Z", EndAt: "2023-07-01T00:00:00Z"}}
+
+	_, err := NewService(t.conf, t.academicYear)
+
+	assert.NotNil(t.T(), err)
+}
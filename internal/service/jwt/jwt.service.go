@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"strings"
 	"time"
 
 	_jwt "github.com/golang-jwt/jwt/v4"
@@ -23,18 +24,64 @@ func NewJwtService(conf cfgldr.Jwt, strategy strategy.JwtStrategy) *serviceImpl
 	}
 }
 
-func (s *serviceImpl) SignAuth(in *entity.Auth) (string, error) {
+func (s *serviceImpl) SignAuth(in *entity.Auth, session *entity.Session) (string, error) {
+	return s.sign(s.authPayloads(in, session.ID.String(), session.Provider, s.conf.ExpiresIn))
+}
+
+// SignImpersonation signs a token of the user for the actor, the session only lives in the cache
+func (s *serviceImpl) SignImpersonation(in *entity.Auth, sessionId string, actorId string, expiresIn int32) (string, error) {
+	payloads := s.authPayloads(in, sessionId, "", expiresIn)
+	payloads.Act = &dto.Actor{Subject: actorId}
+
+	return s.sign(payloads)
+}
+
+func (s *serviceImpl) authPayloads(in *entity.Auth, sessionId string, provider string, expiresIn int32) *dto.TokenPayloadAuth {
 	payloads := &dto.TokenPayloadAuth{
 		RegisteredClaims: _jwt.RegisteredClaims{
 			Issuer:    s.conf.Issuer,
-			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(s.conf.ExpiresIn))),
+			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(expiresIn))),
+			IssuedAt:  _jwt.NewNumericDate(time.Now()),
+		},
+		UserId:    in.UserID,
+		SessionId: sessionId,
+	}
+
+	if s.conf.IdentityClaims {
+		payloads.Role = in.Role
+		payloads.StudentId = in.StudentID
+		payloads.Faculty = in.Faculty
+		payloads.Year = in.Year
+		payloads.Provider = provider
+	}
+
+	return payloads
+}
+
+func (s *serviceImpl) SignService(clientId string, scopes []string, expiresIn int32) (string, error) {
+	payloads := &dto.TokenPayloadService{
+		RegisteredClaims: _jwt.RegisteredClaims{
+			Issuer:    s.conf.Issuer,
+			Subject:   clientId,
+			ExpiresAt: _jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(expiresIn))),
 			IssuedAt:  _jwt.NewNumericDate(time.Now()),
 		},
-		UserId: in.UserID,
+		ClientId: clientId,
+		Scope:    strings.Join(scopes, " "),
 	}
-	token := _jwt.NewWithClaims(_jwt.SigningMethodHS256, payloads)
 
-	tokenStr, err := token.SignedString([]byte(s.conf.Secret))
+	return s.sign(payloads)
+}
+
+func (s *serviceImpl) sign(payloads _jwt.Claims) (string, error) {
+	key := s.strategy.GetSigningKey()
+
+	token := _jwt.NewWithClaims(key.Method, payloads)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	tokenStr, err := token.SignedString(key.SignKey)
 	if err != nil {
 		return "", errors.New("Error while signing the token")
 	}
@@ -46,6 +93,10 @@ func (s *serviceImpl) VerifyAuth(token string) (*_jwt.Token, error) {
 	return _jwt.Parse(token, s.strategy.AuthDecode)
 }
 
+func (s *serviceImpl) GetJwks() *dto.Jwks {
+	return s.strategy.GetJwks()
+}
+
 func (s *serviceImpl) GetConfig() *cfgldr.Jwt {
 	return &s.conf
 }
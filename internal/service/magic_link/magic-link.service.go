@@ -0,0 +1,88 @@
+package magic_link
+
+import (
+	"github.com/go-redis/redis/v8"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	cache_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	keyPrefix       = "magic_link:"
+	cooldownPrefix  = "magic_link_cooldown:"
+	defaultTTL      = 900
+	defaultCooldown = 60
+)
+
+var (
+	ErrInvalidToken    = errors.New("Invalid token")
+	ErrTooManyRequests = errors.New("Too many requests")
+)
+
+type serviceImpl struct {
+	cacheRepository cache_repo.Repository
+	ttl             int
+	cooldown        int
+}
+
+func NewService(cacheRepository cache_repo.Repository, ttl int, cooldown int) *serviceImpl {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	if cooldown <= 0 {
+		cooldown = defaultCooldown
+	}
+
+	return &serviceImpl{
+		cacheRepository: cacheRepository,
+		ttl:             ttl,
+		cooldown:        cooldown,
+	}
+}
+
+// Create returns a new login token of the email, only the hash of the token is kept so a leaked cache cannot be used to login,
+// the cooldown is claimed before the token is made so concurrent requests of the same email cannot all pass it
+func (s *serviceImpl) Create(email string) (string, error) {
+	ok, err := s.cacheRepository.SaveCacheIfAbsent(cooldownPrefix+email, true, s.cooldown)
+	if err != nil {
+		return "", errors.Wrap(err, "error occurs while saving the magic link cooldown")
+	}
+
+	if !ok {
+		return "", ErrTooManyRequests
+	}
+
+	token, err := utils.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.cacheRepository.SaveCache(keyPrefix+utils.Hash([]byte(token)), &dto.MagicLink{Email: email}, s.ttl)
+	if err != nil {
+		return "", errors.Wrap(err, "error occurs while saving the magic link")
+	}
+
+	return token, nil
+}
+
+// Consume returns the magic link once, ErrInvalidToken is returned when it is unknown, expired or already used
+func (s *serviceImpl) Consume(token string) (*dto.MagicLink, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	link := dto.MagicLink{}
+
+	err := s.cacheRepository.PopCache(keyPrefix+utils.Hash([]byte(token)), &link)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidToken
+		}
+
+		return nil, errors.Wrap(err, "error occurs while reading the magic link")
+	}
+
+	return &link, nil
+}
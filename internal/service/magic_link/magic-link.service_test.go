@@ -0,0 +1,121 @@
+package magic_link
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/isd-sgcu/rpkm66-auth/mocks/cache"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MagicLinkServiceTest struct {
+	suite.Suite
+	Email string
+	Token string
+}
+
+func TestMagicLinkService(t *testing.T) {
+	suite.Run(t, new(MagicLinkServiceTest))
+}
+
+func (t *MagicLinkServiceTest) SetupTest() {
+	t.Email = "john.d@alumni.chula.ac.th"
+	t.Token = "token"
+}
+
+func (t *MagicLinkServiceTest) TestCreateSuccess() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCacheIfAbsent", cooldownPrefix+t.Email, true, 30).Return(true, nil)
+	cacheRepo.On("SaveCache", testify.Anything, &dto.MagicLink{Email: t.Email}, 300).Return(nil)
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Create(t.Email)
+
+	assert.Nil(t.T(), err)
+	assert.NotEmpty(t.T(), actual)
+	assert.Equal(t.T(), &dto.MagicLink{Email: t.Email}, cacheRepo.V[keyPrefix+utils.Hash([]byte(actual))])
+	assert.Nil(t.T(), cacheRepo.V[keyPrefix+actual])
+}
+
+func (t *MagicLinkServiceTest) TestCreateCooldown() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCacheIfAbsent", cooldownPrefix+t.Email, true, 30).Return(false, nil)
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Create(t.Email)
+
+	assert.Empty(t.T(), actual)
+	assert.Equal(t.T(), ErrTooManyRequests, err)
+	cacheRepo.AssertNotCalled(t.T(), "SaveCache", testify.Anything, testify.Anything, testify.Anything)
+}
+
+func (t *MagicLinkServiceTest) TestCreateCooldownErr() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCacheIfAbsent", cooldownPrefix+t.Email, true, 30).Return(false, errors.New("connection refused"))
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Create(t.Email)
+
+	assert.Empty(t.T(), actual)
+	assert.NotNil(t.T(), err)
+	cacheRepo.AssertNotCalled(t.T(), "SaveCache", testify.Anything, testify.Anything, testify.Anything)
+}
+
+func (t *MagicLinkServiceTest) TestCreateCacheErr() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCacheIfAbsent", cooldownPrefix+t.Email, true, defaultCooldown).Return(true, nil)
+	cacheRepo.On("SaveCache", testify.Anything, testify.Anything, defaultTTL).Return(errors.New("connection refused"))
+
+	srv := NewService(cacheRepo, 0, 0)
+
+	actual, err := srv.Create(t.Email)
+
+	assert.Empty(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *MagicLinkServiceTest) TestConsumeSuccess() {
+	want := &dto.MagicLink{Email: t.Email}
+
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+utils.Hash([]byte(t.Token)), &dto.MagicLink{}).Return(want, nil)
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Consume(t.Token)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *MagicLinkServiceTest) TestConsumeUnknownToken() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+utils.Hash([]byte(t.Token)), &dto.MagicLink{}).Return(nil, redis.Nil)
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Consume(t.Token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidToken, err)
+}
+
+func (t *MagicLinkServiceTest) TestConsumeEmptyToken() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+
+	srv := NewService(cacheRepo, 300, 30)
+
+	actual, err := srv.Consume("")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidToken, err)
+	cacheRepo.AssertNotCalled(t.T(), "PopCache", testify.Anything, testify.Anything)
+}
@@ -0,0 +1,161 @@
+package permission
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
+	permission_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/permission"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultRefreshInterval = 60
+
+// serviceImpl answers from an in-memory copy of the role_permissions table, Validate and every guarded method would otherwise query it per request
+type serviceImpl struct {
+	repo            permission_repo.Repository
+	refreshInterval time.Duration
+	mu              sync.RWMutex
+	permissions     map[role.Role][]string
+	stop            chan struct{}
+	done            chan struct{}
+}
+
+func NewService(repo permission_repo.Repository, refreshInterval int) *serviceImpl {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+
+	return &serviceImpl{
+		repo:            repo,
+		refreshInterval: time.Second * time.Duration(refreshInterval),
+	}
+}
+
+// SeedDefaults writes the code defined permissions once, an operator can change the table afterwards without being overwritten
+func (s *serviceImpl) SeedDefaults() error {
+	var count int64
+
+	err := s.repo.Count(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	var roles []string
+	for r := range role.DEFAULT_PERMISSIONS {
+		roles = append(roles, string(r))
+	}
+	sort.Strings(roles)
+
+	var permissions []*entity.RolePermission
+	for _, r := range roles {
+		for _, p := range role.DEFAULT_PERMISSIONS[role.Role(r)] {
+			permissions = append(permissions, &entity.RolePermission{
+				Role:       r,
+				Permission: string(p),
+			})
+		}
+	}
+
+	return s.repo.CreateBatch(&permissions)
+}
+
+// Load replaces the in-memory copy with the current table
+func (s *serviceImpl) Load() error {
+	var rolePermissions []*entity.RolePermission
+
+	err := s.repo.FindAll(&rolePermissions)
+	if err != nil {
+		return err
+	}
+
+	permissions := map[role.Role][]string{}
+	for _, p := range rolePermissions {
+		permissions[role.Role(p.Role)] = append(permissions[role.Role(p.Role)], p.Permission)
+	}
+
+	s.mu.Lock()
+	s.permissions = permissions
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Start reloads the table in the background until Close, a failed reload keeps serving the last copy
+func (s *serviceImpl) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.Load(); err != nil {
+					log.Error().
+						Err(err).
+						Str("service", "auth").
+						Str("module", "permission").
+						Msg("Cannot reload the role permissions")
+				}
+			}
+		}
+	}()
+}
+
+func (s *serviceImpl) Close() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+func (s *serviceImpl) FindByRole(r role.Role) ([]string, error) {
+	s.mu.RLock()
+	loaded := s.permissions != nil
+	s.mu.RUnlock()
+
+	if !loaded {
+		if err := s.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	permissions := []string{}
+	permissions = append(permissions, s.permissions[r]...)
+
+	return permissions, nil
+}
+
+func (s *serviceImpl) HasPermission(r role.Role, permission string) (bool, error) {
+	permissions, err := s.FindByRole(r)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,198 @@
+package permission
+
+import (
+	"testing"
+	"time"
+
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
+	mock "github.com/isd-sgcu/rpkm66-auth/mocks/permission"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PermissionServiceTest struct {
+	suite.Suite
+	RolePermissions []*entity.RolePermission
+}
+
+func TestPermissionService(t *testing.T) {
+	suite.Run(t, new(PermissionServiceTest))
+}
+
+func (t *PermissionServiceTest) SetupTest() {
+	t.RolePermissions = []*entity.RolePermission{
+		{Role: string(role.BAAN_STAFF), Permission: string(role.USER_CHECKIN)},
+		{Role: string(role.BAAN_STAFF), Permission: string(role.USER_READ)},
+	}
+}
+
+func (t *PermissionServiceTest) TestSeedDefaultsEmptyTable() {
+	var count int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Count", &count).Return(int64(0), nil)
+	repo.On("CreateBatch", testify.MatchedBy(func(in *[]*entity.RolePermission) bool {
+		total := 0
+		for _, permissions := range role.DEFAULT_PERMISSIONS {
+			total += len(permissions)
+		}
+
+		return len(*in) == total
+	})).Return(nil)
+
+	srv := NewService(repo, 0)
+
+	err := srv.SeedDefaults()
+
+	assert.Nil(t.T(), err)
+	repo.AssertNumberOfCalls(t.T(), "CreateBatch", 1)
+}
+
+func (t *PermissionServiceTest) TestSeedDefaultsKeepExisting() {
+	var count int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Count", &count).Return(int64(3), nil)
+
+	srv := NewService(repo, 0)
+
+	err := srv.SeedDefaults()
+
+	assert.Nil(t.T(), err)
+	repo.AssertNumberOfCalls(t.T(), "CreateBatch", 0)
+}
+
+func (t *PermissionServiceTest) TestSeedDefaultsInternalErr() {
+	var count int64
+
+	repo := &mock.RepositoryMock{}
+	repo.On("Count", &count).Return(int64(0), nil)
+	repo.On("CreateBatch", testify.Anything).Return(errors.New("Cannot connect to database"))
+
+	srv := NewService(repo, 0)
+
+	err := srv.SeedDefaults()
+
+	assert.NotNil(t.T(), err)
+}
+
+func (t *PermissionServiceTest) TestFindByRoleSuccess() {
+	want := []string{string(role.USER_CHECKIN), string(role.USER_READ)}
+
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions, nil)
+
+	srv := NewService(repo, 0)
+
+	actual, err := srv.FindByRole(role.BAAN_STAFF)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+
+	actual, err = srv.FindByRole(role.USER)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), []string{}, actual)
+	repo.AssertNumberOfCalls(t.T(), "FindAll", 1)
+}
+
+func (t *PermissionServiceTest) TestLoadReplacesPermissions() {
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions, nil).Once()
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions[:1], nil).Once()
+
+	srv := NewService(repo, 0)
+
+	assert.Nil(t.T(), srv.Load())
+
+	allowed, err := srv.HasPermission(role.BAAN_STAFF, string(role.USER_READ))
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), allowed)
+
+	assert.Nil(t.T(), srv.Load())
+
+	allowed, err = srv.HasPermission(role.BAAN_STAFF, string(role.USER_READ))
+
+	assert.Nil(t.T(), err)
+	assert.False(t.T(), allowed)
+}
+
+func (t *PermissionServiceTest) TestStartReloadsInBackground() {
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions[:1], nil).Once()
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions, nil)
+
+	srv := NewService(repo, 0)
+	srv.refreshInterval = time.Millisecond
+
+	assert.Nil(t.T(), srv.Load())
+
+	srv.Start()
+	defer srv.Close()
+
+	assert.Eventually(t.T(), func() bool {
+		allowed, _ := srv.HasPermission(role.BAAN_STAFF, string(role.USER_READ))
+		return allowed
+	}, time.Second, time.Millisecond)
+}
+
+func (t *PermissionServiceTest) TestReloadFailureKeepsPermissions() {
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions, nil).Once()
+	repo.On("FindAll", &rolePermissions).Return(nil, errors.New("Cannot connect to database"))
+
+	srv := NewService(repo, 0)
+
+	assert.Nil(t.T(), srv.Load())
+	assert.NotNil(t.T(), srv.Load())
+
+	allowed, err := srv.HasPermission(role.BAAN_STAFF, string(role.USER_CHECKIN))
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), allowed)
+}
+
+func (t *PermissionServiceTest) TestHasPermission() {
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(t.RolePermissions, nil)
+
+	srv := NewService(repo, 0)
+
+	allowed, err := srv.HasPermission(role.BAAN_STAFF, string(role.USER_CHECKIN))
+
+	assert.Nil(t.T(), err)
+	assert.True(t.T(), allowed)
+
+	allowed, err = srv.HasPermission(role.BAAN_STAFF, string(role.ROLE_WRITE))
+
+	assert.Nil(t.T(), err)
+	assert.False(t.T(), allowed)
+}
+
+func (t *PermissionServiceTest) TestHasPermissionInternalErr() {
+	var rolePermissions []*entity.RolePermission
+
+	repo := &mock.RepositoryMock{}
+	repo.On("FindAll", &rolePermissions).Return(nil, errors.New("Cannot connect to database"))
+
+	srv := NewService(repo, 0)
+
+	allowed, err := srv.HasPermission(role.BAAN_STAFF, string(role.USER_CHECKIN))
+
+	assert.NotNil(t.T(), err)
+	assert.False(t.T(), allowed)
+}
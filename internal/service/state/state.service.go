@@ -0,0 +1,89 @@
+package state
+
+import (
+	"github.com/go-redis/redis/v8"
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	cache_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	keyPrefix  = "login_state:"
+	defaultTTL = 600
+)
+
+var ErrInvalidState = errors.New("Invalid state")
+
+type serviceImpl struct {
+	cacheRepository cache_repo.Repository
+	ttl             int
+}
+
+func NewService(cacheRepository cache_repo.Repository, ttl int) *serviceImpl {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &serviceImpl{
+		cacheRepository: cacheRepository,
+		ttl:             ttl,
+	}
+}
+
+// Create generates the state, nonce and PKCE verifier of a new login and keeps them until the callback
+func (s *serviceImpl) Create(provider role.Provider) (*dto.LoginState, error) {
+	state, err := utils.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := utils.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := utils.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState := &dto.LoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Provider:     provider,
+	}
+
+	err = s.cacheRepository.SaveCache(keyPrefix+state, loginState, s.ttl)
+	if err != nil {
+		return nil, errors.Wrap(err, "error occurs while saving the login state")
+	}
+
+	return loginState, nil
+}
+
+// Consume returns the login state once, ErrInvalidState is returned when it is unknown, expired, already used or issued for another provider
+func (s *serviceImpl) Consume(provider role.Provider, state string) (*dto.LoginState, error) {
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+
+	loginState := dto.LoginState{}
+
+	err := s.cacheRepository.PopCache(keyPrefix+state, &loginState)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidState
+		}
+
+		return nil, errors.Wrap(err, "error occurs while reading the login state")
+	}
+
+	if loginState.Provider != provider {
+		return nil, ErrInvalidState
+	}
+
+	return &loginState, nil
+}
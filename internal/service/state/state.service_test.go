@@ -0,0 +1,133 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/mocks/cache"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type StateServiceTest struct {
+	suite.Suite
+	LoginState *dto.LoginState
+}
+
+func TestStateService(t *testing.T) {
+	suite.Run(t, new(StateServiceTest))
+}
+
+func (t *StateServiceTest) SetupTest() {
+	t.LoginState = &dto.LoginState{
+		State:        "state",
+		Nonce:        "nonce",
+		CodeVerifier: "code-verifier",
+		Provider:     role.GOOGLE,
+	}
+}
+
+func (t *StateServiceTest) TestCreateSuccess() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", testify.Anything, testify.Anything, 300).Return(nil)
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Create(role.GOOGLE)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), role.Provider(role.GOOGLE), actual.Provider)
+	assert.NotEmpty(t.T(), actual.State)
+	assert.NotEmpty(t.T(), actual.Nonce)
+	assert.NotEmpty(t.T(), actual.CodeVerifier)
+	assert.NotEqual(t.T(), actual.State, actual.CodeVerifier)
+	assert.Equal(t.T(), actual, cacheRepo.V[keyPrefix+actual.State])
+}
+
+func (t *StateServiceTest) TestCreateDefaultTTL() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", testify.Anything, testify.Anything, defaultTTL).Return(nil)
+
+	srv := NewService(cacheRepo, 0)
+
+	_, err := srv.Create(role.GOOGLE)
+
+	assert.Nil(t.T(), err)
+}
+
+func (t *StateServiceTest) TestCreateCacheErr() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("SaveCache", testify.Anything, testify.Anything, 300).Return(errors.New("connection refused"))
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Create(role.GOOGLE)
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *StateServiceTest) TestConsumeSuccess() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+t.LoginState.State, &dto.LoginState{}).Return(t.LoginState, nil)
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Consume(role.GOOGLE, t.LoginState.State)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), t.LoginState, actual)
+}
+
+func (t *StateServiceTest) TestConsumeUnknownState() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+t.LoginState.State, &dto.LoginState{}).Return(nil, redis.Nil)
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Consume(role.GOOGLE, t.LoginState.State)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidState, err)
+}
+
+func (t *StateServiceTest) TestConsumeAnotherProvider() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+t.LoginState.State, &dto.LoginState{}).Return(t.LoginState, nil)
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Consume("entra", t.LoginState.State)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidState, err)
+}
+
+func (t *StateServiceTest) TestConsumeEmptyState() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Consume(role.GOOGLE, "")
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrInvalidState, err)
+	cacheRepo.AssertNotCalled(t.T(), "PopCache", testify.Anything, testify.Anything)
+}
+
+func (t *StateServiceTest) TestConsumeCacheErr() {
+	cacheRepo := &cache.RepositoryMock{V: map[string]interface{}{}}
+	cacheRepo.On("PopCache", keyPrefix+t.LoginState.State, &dto.LoginState{}).Return(nil, errors.New("connection refused"))
+
+	srv := NewService(cacheRepo, 300)
+
+	actual, err := srv.Consume(role.GOOGLE, t.LoginState.State)
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+	assert.NotEqual(t.T(), ErrInvalidState, err)
+}
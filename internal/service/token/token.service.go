@@ -1,6 +1,7 @@
 package token
 
 import (
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -28,8 +29,14 @@ func NewService(jwtService jwt_svc.Service, cacheRepository cache_repo.Repositor
 	}
 }
 
-func (s *Service) CreateCredentials(auth *entity.Auth, secret string) (*auth_proto.Credential, error) {
-	token, err := s.jwtService.SignAuth(auth)
+const (
+	defaultRefreshExpiresIn = 604800
+	defaultMaxSessionAge    = 2592000
+)
+
+// CreateCredentials also stamps the refresh token expiry onto the session, the refresh token never outlives the session itself
+func (s *Service) CreateCredentials(auth *entity.Auth, session *entity.Session, secret string) (*auth_proto.Credential, error) {
+	token, err := s.jwtService.SignAuth(auth, session)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +46,7 @@ func (s *Service) CreateCredentials(auth *entity.Auth, secret string) (*auth_pro
 		Role:  role.Role(auth.Role),
 	}
 
-	err = s.cacheRepository.SaveCache(auth.UserID, &cache, int(s.jwtService.GetConfig().ExpiresIn))
+	err = s.cacheRepository.SaveCache(session.ID.String(), &cache, int(s.jwtService.GetConfig().ExpiresIn))
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -49,33 +56,199 @@ func (s *Service) CreateCredentials(auth *entity.Auth, secret string) (*auth_pro
 		return nil, errors.New("Internal service error")
 	}
 
+	conf := s.jwtService.GetConfig()
+	now := time.Now()
+
+	maxSessionAge := conf.MaxSessionAge
+	if maxSessionAge <= 0 {
+		maxSessionAge = defaultMaxSessionAge
+	}
+
+	refreshExpiresIn := conf.RefreshExpiresIn
+	if refreshExpiresIn <= 0 {
+		refreshExpiresIn = defaultRefreshExpiresIn
+	}
+
+	if session.ExpiresAt.IsZero() {
+		session.ExpiresAt = now.Add(time.Second * time.Duration(maxSessionAge))
+	}
+
+	session.RefreshExpiresAt = now.Add(time.Second * time.Duration(refreshExpiresIn))
+	if session.RefreshExpiresAt.After(session.ExpiresAt) {
+		session.RefreshExpiresAt = session.ExpiresAt
+	}
+
 	credential := &auth_proto.Credential{
-		AccessToken:  token,
-		RefreshToken: s.CreateRefreshToken(),
-		ExpiresIn:    s.jwtService.GetConfig().ExpiresIn,
+		AccessToken:      token,
+		RefreshToken:     s.CreateRefreshToken(),
+		ExpiresIn:        conf.ExpiresIn,
+		RefreshExpiresIn: int32(session.RefreshExpiresAt.Sub(now).Seconds()),
 	}
 
 	return credential, nil
 }
 
+const defaultImpersonationExpiresIn = 900
+
+// CreateImpersonationCredentials issues a token of the user for the actor, it has no session row and no refresh token so it dies with the cache entry
+func (s *Service) CreateImpersonationCredentials(auth *entity.Auth, actorId string) (*auth_proto.Credential, error) {
+	expiresIn := s.jwtService.GetConfig().ImpersonationExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultImpersonationExpiresIn
+	}
+
+	sessionId := uuid.New().String()
+
+	token, err := s.jwtService.SignImpersonation(auth, sessionId, actorId, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := dto.CacheAuth{
+		Token: token,
+		Role:  role.Role(auth.Role),
+		Actor: actorId,
+	}
+
+	err = s.cacheRepository.SaveCache(sessionId, &cache, int(expiresIn))
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "impersonate").
+			Msg("Cannot connect to cache server")
+		return nil, errors.New("Internal service error")
+	}
+
+	return &auth_proto.Credential{
+		AccessToken: token,
+		ExpiresIn:   expiresIn,
+	}, nil
+}
+
+const defaultServiceExpiresIn = 300
+
+// CreateServiceToken issues a stateless token for the client credentials grant, it cannot be revoked so it is kept short lived
+func (s *Service) CreateServiceToken(clientId string, scopes []string) (*auth_proto.IssueServiceTokenResponse, error) {
+	expiresIn := s.jwtService.GetConfig().ServiceExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultServiceExpiresIn
+	}
+
+	token, err := s.jwtService.SignService(clientId, scopes, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth_proto.IssueServiceTokenResponse{
+		AccessToken: token,
+		TokenType:   role.BEARER,
+		ExpiresIn:   expiresIn,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// ErrCacheUnavailable means the token could not be checked against the cache, it says nothing about the token itself
+var ErrCacheUnavailable = errors.New("Internal service error")
+
 func (s *Service) Validate(token string) (*dto.UserCredential, error) {
-	t, err := s.jwtService.VerifyAuth(token)
+	payload, cache, err := s.verify(token)
 	if err != nil {
 		return nil, err
 	}
 
+	if clientId, ok := serviceClientId(payload); ok {
+		scope, _ := payload["scope"].(string)
+
+		return &dto.UserCredential{
+			ClientId: clientId,
+			Scopes:   strings.Fields(scope),
+		}, nil
+	}
+
+	return &dto.UserCredential{
+		UserId:    payload["user_id"].(string),
+		SessionId: payload["session_id"].(string),
+		Role:      cache.Role,
+		Actor:     cache.Actor,
+	}, nil
+}
+
+// Introspect follows RFC 7662, a token that is invalid, expired or revoked is reported as inactive instead of an error
+func (s *Service) Introspect(token string) (*dto.TokenIntrospection, error) {
+	payload, cache, err := s.verify(token)
+	if err != nil {
+		if err == ErrCacheUnavailable {
+			return nil, err
+		}
+
+		return &dto.TokenIntrospection{Active: false}, nil
+	}
+
+	introspection := &dto.TokenIntrospection{
+		Active:    true,
+		Issuer:    payload["iss"].(string),
+		TokenType: role.BEARER,
+	}
+
+	if clientId, ok := serviceClientId(payload); ok {
+		introspection.Subject = clientId
+		introspection.ClientId = clientId
+		introspection.Scope, _ = payload["scope"].(string)
+	} else {
+		introspection.Subject = payload["user_id"].(string)
+		introspection.Role = string(cache.Role)
+		introspection.SessionId = payload["session_id"].(string)
+		introspection.Actor = cache.Actor
+	}
+
+	if exp, ok := payload["exp"].(float64); ok {
+		introspection.ExpiresAt = int64(exp)
+	}
+
+	if iat, ok := payload["iat"].(float64); ok {
+		introspection.IssuedAt = int64(iat)
+	}
+
+	return introspection, nil
+}
+
+func (s *Service) verify(token string) (jwt.MapClaims, *dto.CacheAuth, error) {
+	t, err := s.jwtService.VerifyAuth(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	payload := t.Claims.(jwt.MapClaims)
 
 	if payload["iss"] != s.jwtService.GetConfig().Issuer {
-		return nil, errors.New("Invalid token")
+		return nil, nil, errors.New("Invalid token")
 	}
 
 	if time.Unix(int64(payload["exp"].(float64)), 0).Before(time.Now()) {
-		return nil, errors.New("Token is expired")
+		return nil, nil, errors.New("Token is expired")
+	}
+
+	// a service token is not bound to a session, there is no cache entry to check
+	if _, ok := payload["client_id"]; ok {
+		if _, ok := serviceClientId(payload); !ok {
+			return nil, nil, errors.New("Invalid token")
+		}
+
+		return payload, nil, nil
+	}
+
+	if _, ok := payload["user_id"].(string); !ok {
+		return nil, nil, errors.New("Invalid token")
+	}
+
+	sessionId, ok := payload["session_id"].(string)
+	if !ok || sessionId == "" {
+		return nil, nil, errors.New("Invalid token")
 	}
 
 	cache := dto.CacheAuth{}
-	err = s.cacheRepository.GetCache(payload["user_id"].(string), &cache)
+	err = s.cacheRepository.GetCache(sessionId, &cache)
 	if err != nil {
 		if err != redis.Nil {
 			log.Error().
@@ -83,22 +256,79 @@ func (s *Service) Validate(token string) (*dto.UserCredential, error) {
 				Str("service", "auth").
 				Str("module", "validate").
 				Msg("Cannot connect to cache server")
-			return nil, errors.New("Internal service error")
+			return nil, nil, ErrCacheUnavailable
 		}
 
-		return nil, errors.New("Invalid token")
+		return nil, nil, errors.New("Invalid token")
 	}
 
 	if cache.Token != token {
-		return nil, errors.New("Invalid token")
+		return nil, nil, errors.New("Invalid token")
 	}
 
-	return &dto.UserCredential{
-		UserId: payload["user_id"].(string),
-		Role:   cache.Role,
-	}, nil
+	return payload, &cache, nil
+}
+
+// UpdateRole changes the role of a live session so it takes effect without logging in again,
+// with identity claims the role is signed into the access token so the token is revoked instead and the next refresh issues one with the new role
+func (s *Service) UpdateRole(sessionId string, userRole role.Role) error {
+	if s.jwtService.GetConfig().IdentityClaims {
+		return s.RemoveCredentials(sessionId)
+	}
+
+	cache := dto.CacheAuth{}
+	err := s.cacheRepository.GetCache(sessionId, &cache)
+	if err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "update role").
+			Msg("Cannot connect to cache server")
+		return ErrCacheUnavailable
+	}
+
+	cache.Role = userRole
+
+	err = s.cacheRepository.SaveCache(sessionId, &cache, int(s.jwtService.GetConfig().ExpiresIn))
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "update role").
+			Msg("Cannot connect to cache server")
+		return ErrCacheUnavailable
+	}
+
+	return nil
 }
 
 func (s *Service) CreateRefreshToken() string {
 	return uuid.New().String()
 }
+
+func (s *Service) RemoveCredentials(sessionId string) error {
+	err := s.cacheRepository.RemoveCache(sessionId)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "auth").
+			Str("module", "remove credentials").
+			Msg("Cannot connect to cache server")
+		return errors.New("Internal service error")
+	}
+
+	return nil
+}
+
+func (s *Service) GetJwks() *dto.Jwks {
+	return s.jwtService.GetJwks()
+}
+
+func serviceClientId(payload jwt.MapClaims) (string, bool) {
+	clientId, ok := payload["client_id"].(string)
+	return clientId, ok && clientId != ""
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/isd-sgcu/rpkm66-auth/mocks/cache"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 )
@@ -26,6 +27,7 @@ type TokenServiceTest struct {
 	suite.Suite
 	Credential   *auth_proto.Credential
 	Auth         *entity.Auth
+	Session      *entity.Session
 	Token        *jwt.Token
 	TokenDecoded jwt.MapClaims
 	Payload      *dto.TokenPayloadAuth
@@ -38,15 +40,18 @@ func TestTokenService(t *testing.T) {
 
 func (t *TokenServiceTest) SetupTest() {
 	t.Conf = &cfgldr.Jwt{
-		Secret:    faker.Word(),
-		ExpiresIn: 3600,
-		Issuer:    faker.Word(),
+		Secret:           faker.Word(),
+		ExpiresIn:        3600,
+		RefreshExpiresIn: 604800,
+		MaxSessionAge:    2592000,
+		Issuer:           faker.Word(),
 	}
 
 	t.Credential = &auth_proto.Credential{
-		AccessToken:  faker.Word(),
-		RefreshToken: faker.Word(),
-		ExpiresIn:    3600,
+		AccessToken:      faker.Word(),
+		RefreshToken:     faker.Word(),
+		ExpiresIn:        3600,
+		RefreshExpiresIn: 604800,
 	}
 
 	t.Auth = &entity.Auth{
@@ -56,9 +61,18 @@ func (t *TokenServiceTest) SetupTest() {
 			UpdatedAt: time.Time{},
 			DeletedAt: gorm.DeletedAt{},
 		},
-		UserID:       faker.UUIDDigit(),
-		Role:         auth.USER,
-		RefreshToken: faker.Word(),
+		UserID: faker.UUIDDigit(),
+		Role:   auth.USER,
+	}
+
+	t.Session = &entity.Session{
+		Base: base.Base{
+			ID:        uuid.New(),
+			CreatedAt: time.Time{},
+			UpdatedAt: time.Time{},
+			DeletedAt: gorm.DeletedAt{},
+		},
+		UserID: t.Auth.UserID,
 	}
 
 	t.Token = &jwt.Token{
@@ -68,7 +82,8 @@ func (t *TokenServiceTest) SetupTest() {
 				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * time.Duration(t.Conf.ExpiresIn))),
 				IssuedAt:  jwt.NewNumericDate(time.Now()),
 			},
-			UserId: t.Auth.UserID,
+			UserId:    t.Auth.UserID,
+			SessionId: t.Session.ID.String(),
 		},
 		Valid: true,
 	}
@@ -79,7 +94,8 @@ func (t *TokenServiceTest) SetupTest() {
 			ExpiresAt: t.Token.Claims.(dto.TokenPayloadAuth).ExpiresAt,
 			IssuedAt:  t.Token.Claims.(dto.TokenPayloadAuth).IssuedAt,
 		},
-		UserId: t.Auth.UserID,
+		UserId:    t.Auth.UserID,
+		SessionId: t.Session.ID.String(),
 	}
 
 	t.TokenDecoded = jwt.MapClaims{}
@@ -87,6 +103,7 @@ func (t *TokenServiceTest) SetupTest() {
 	t.TokenDecoded["iat"] = t.Token.Claims.(dto.TokenPayloadAuth).IssuedAt
 	t.TokenDecoded["exp"] = float64(time.Now().Add(time.Second * time.Duration(t.Conf.ExpiresIn)).UnixNano())
 	t.TokenDecoded["user_id"] = t.Auth.UserID
+	t.TokenDecoded["session_id"] = t.Session.ID.String()
 	t.TokenDecoded["role"] = t.Auth.Role
 }
 
@@ -94,7 +111,7 @@ func (t *TokenServiceTest) TestCreateCredentialsSuccess() {
 	want := t.Credential
 
 	jwtSrv := mock.JwtServiceMock{}
-	jwtSrv.On("SignAuth", t.Auth).Return(t.Credential.AccessToken, nil)
+	jwtSrv.On("SignAuth", t.Auth, t.Session).Return(t.Credential.AccessToken, nil)
 	jwtSrv.On("GetConfig").Return(t.Conf, nil)
 
 	cacheData := &dto.CacheAuth{
@@ -105,28 +122,87 @@ func (t *TokenServiceTest) TestCreateCredentialsSuccess() {
 	cacheRepo := cache.RepositoryMock{
 		V: map[string]interface{}{},
 	}
-	cacheRepo.On("SaveCache", t.TokenDecoded["user_id"], cacheData, 3600).Return(nil)
+	cacheRepo.On("SaveCache", t.TokenDecoded["session_id"], cacheData, 3600).Return(nil)
 
 	srv := NewService(&jwtSrv, &cacheRepo)
 
-	actual, err := srv.CreateCredentials(t.Auth, "asuperstrong32bitpasswordgohere!")
+	actual, err := srv.CreateCredentials(t.Auth, t.Session, "asuperstrong32bitpasswordgohere!")
 
 	assert.Nilf(t.T(), err, "error: %v", err)
 	assert.Equal(t.T(), want.AccessToken, actual.AccessToken)
 	assert.Equal(t.T(), want.ExpiresIn, actual.ExpiresIn)
+	assert.InDelta(t.T(), want.RefreshExpiresIn, actual.RefreshExpiresIn, 1)
+	assert.WithinDuration(t.T(), time.Now().Add(time.Second*time.Duration(t.Conf.MaxSessionAge)), t.Session.ExpiresAt, time.Second)
+	assert.WithinDuration(t.T(), time.Now().Add(time.Second*time.Duration(t.Conf.RefreshExpiresIn)), t.Session.RefreshExpiresAt, time.Second)
+}
+
+func (t *TokenServiceTest) TestCreateCredentialsRefreshExpiryCappedBySession() {
+	expiresAt := time.Now().Add(time.Hour)
+	t.Session.ExpiresAt = expiresAt
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("SignAuth", t.Auth, t.Session).Return(t.Credential.AccessToken, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheData := &dto.CacheAuth{
+		Token: t.Credential.AccessToken,
+		Role:  auth.USER,
+	}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", t.TokenDecoded["session_id"], cacheData, 3600).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.CreateCredentials(t.Auth, t.Session, "asuperstrong32bitpasswordgohere!")
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.InDelta(t.T(), 3600, actual.RefreshExpiresIn, 1)
+	assert.Equal(t.T(), expiresAt, t.Session.ExpiresAt)
+	assert.Equal(t.T(), expiresAt, t.Session.RefreshExpiresAt)
+}
+
+func (t *TokenServiceTest) TestCreateCredentialsDefaultExpiry() {
+	t.Conf.RefreshExpiresIn = 0
+	t.Conf.MaxSessionAge = 0
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("SignAuth", t.Auth, t.Session).Return(t.Credential.AccessToken, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheData := &dto.CacheAuth{
+		Token: t.Credential.AccessToken,
+		Role:  auth.USER,
+	}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", t.TokenDecoded["session_id"], cacheData, 3600).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.CreateCredentials(t.Auth, t.Session, "asuperstrong32bitpasswordgohere!")
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.InDelta(t.T(), defaultRefreshExpiresIn, actual.RefreshExpiresIn, 1)
+	assert.WithinDuration(t.T(), time.Now().Add(time.Second*defaultMaxSessionAge), t.Session.ExpiresAt, time.Second)
+	assert.WithinDuration(t.T(), time.Now().Add(time.Second*defaultRefreshExpiresIn), t.Session.RefreshExpiresAt, time.Second)
 }
 
 func (t *TokenServiceTest) TestCreateCredentialsInternalErr() {
 	want := errors.New("Error while signing the token")
 
 	jwtSrv := mock.JwtServiceMock{}
-	jwtSrv.On("SignAuth", t.Auth).Return("", errors.New("Error while signing the token"))
+	jwtSrv.On("SignAuth", t.Auth, t.Session).Return("", errors.New("Error while signing the token"))
 
 	cacheRepo := cache.RepositoryMock{}
 
 	srv := NewService(&jwtSrv, &cacheRepo)
 
-	actual, err := srv.CreateCredentials(t.Auth, "asuperstrong32bitpasswordgohere!")
+	actual, err := srv.CreateCredentials(t.Auth, t.Session, "asuperstrong32bitpasswordgohere!")
 
 	var credential *auth_proto.Credential
 
@@ -136,8 +212,9 @@ func (t *TokenServiceTest) TestCreateCredentialsInternalErr() {
 
 func (t *TokenServiceTest) TestValidateAccessTokenSuccess() {
 	want := &dto.UserCredential{
-		UserId: t.Token.Claims.(dto.TokenPayloadAuth).UserId,
-		Role:   auth.Role(t.Auth.Role),
+		UserId:    t.Token.Claims.(dto.TokenPayloadAuth).UserId,
+		SessionId: t.Token.Claims.(dto.TokenPayloadAuth).SessionId,
+		Role:      auth.Role(t.Auth.Role),
 	}
 	token := faker.Word()
 
@@ -153,7 +230,7 @@ func (t *TokenServiceTest) TestValidateAccessTokenSuccess() {
 		Role:  auth.USER,
 	}
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
 
 	srv := NewService(&jwtSrv, &cacheRepo)
 
@@ -180,6 +257,14 @@ func (t *TokenServiceTest) TestValidateAccessTokenInvalidToken() {
 		Claims: t.TokenDecoded,
 		Valid:  true,
 	}, "Token is expired")
+
+	t.TokenDecoded["exp"] = float64(time.Now().Add(time.Second * time.Duration(t.Conf.ExpiresIn)).Unix())
+	delete(t.TokenDecoded, "session_id")
+
+	testValidateAccessTokenInvalidTokenInvalidCase(t.T(), t.Conf, &jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, "Invalid token")
 }
 
 func testValidateAccessTokenInvalidTokenMalformedToken(t *testing.T, refreshToken string) {
@@ -238,7 +323,7 @@ func (t *TokenServiceTest) TestValidateAccessTokenNotMatchWithCache() {
 	jwtSrv.On("GetConfig").Return(t.Conf, nil)
 
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(&cacheAuth, nil)
 
 	srv := NewService(&jwtSrv, &cacheRepo)
 
@@ -260,7 +345,7 @@ func (t *TokenServiceTest) TestValidateCacheNotFoundUser() {
 	jwtSrv.On("GetConfig").Return(t.Conf, nil)
 
 	cacheRepo := cache.RepositoryMock{}
-	cacheRepo.On("GetCache", t.TokenDecoded["user_id"], &dto.CacheAuth{}).Return(nil, redis.Nil)
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(nil, redis.Nil)
 
 	srv := NewService(&jwtSrv, &cacheRepo)
 
@@ -269,3 +354,351 @@ func (t *TokenServiceTest) TestValidateCacheNotFoundUser() {
 	assert.Nil(t.T(), actual)
 	assert.Equal(t.T(), want.Error(), err.Error())
 }
+
+func (t *TokenServiceTest) TestIntrospectActive() {
+	token := faker.Word()
+	issuedAt := float64(time.Now().Unix())
+	expiresAt := float64(time.Now().Add(time.Second * time.Duration(t.Conf.ExpiresIn)).Unix())
+
+	t.TokenDecoded["iat"] = issuedAt
+	t.TokenDecoded["exp"] = expiresAt
+
+	want := &dto.TokenIntrospection{
+		Active:    true,
+		ExpiresAt: int64(expiresAt),
+		IssuedAt:  int64(issuedAt),
+		Issuer:    t.Conf.Issuer,
+		Subject:   t.Auth.UserID,
+		Role:      t.Auth.Role,
+		SessionId: t.Session.ID.String(),
+		TokenType: auth.BEARER,
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: token, Role: auth.USER}, nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.Introspect(token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) TestIntrospectInactive() {
+	want := &dto.TokenIntrospection{Active: false}
+
+	malformed := faker.Word()
+	revoked := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", malformed).Return(nil, errors.New("token is malformed"))
+	jwtSrv.On("VerifyAuth", revoked).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.Introspect(malformed)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+
+	actual, err = srv.Introspect(revoked)
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) TestIntrospectCacheErr() {
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(nil, errors.New("Cannot connect to cache server"))
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.Introspect(token)
+
+	assert.Nil(t.T(), actual)
+	assert.Equal(t.T(), ErrCacheUnavailable, err)
+}
+
+func (t *TokenServiceTest) TestUpdateRoleSuccess() {
+	sessionId := t.Session.ID.String()
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("GetCache", sessionId, &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: token, Role: auth.USER}, nil)
+	cacheRepo.On("SaveCache", sessionId, &dto.CacheAuth{Token: token, Role: auth.ADMIN}, int(t.Conf.ExpiresIn)).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	err := srv.UpdateRole(sessionId, auth.ADMIN)
+
+	assert.Nil(t.T(), err)
+	cacheRepo.AssertNumberOfCalls(t.T(), "SaveCache", 1)
+}
+
+func (t *TokenServiceTest) TestUpdateRoleExpiredSession() {
+	sessionId := t.Session.ID.String()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", sessionId, &dto.CacheAuth{}).Return(nil, redis.Nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	err := srv.UpdateRole(sessionId, auth.ADMIN)
+
+	assert.Nil(t.T(), err)
+	cacheRepo.AssertNumberOfCalls(t.T(), "SaveCache", 0)
+}
+
+func (t *TokenServiceTest) TestUpdateRoleIdentityClaims() {
+	sessionId := t.Session.ID.String()
+	t.Conf.IdentityClaims = true
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("RemoveCache", sessionId).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	err := srv.UpdateRole(sessionId, auth.ADMIN)
+
+	assert.Nil(t.T(), err)
+	cacheRepo.AssertCalled(t.T(), "RemoveCache", sessionId)
+	cacheRepo.AssertNumberOfCalls(t.T(), "SaveCache", 0)
+}
+
+func (t *TokenServiceTest) TestRemoveCredentialsSuccess() {
+	jwtSrv := mock.JwtServiceMock{}
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("RemoveCache", t.Session.ID.String()).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	err := srv.RemoveCredentials(t.Session.ID.String())
+
+	assert.Nil(t.T(), err)
+	cacheRepo.AssertCalled(t.T(), "RemoveCache", t.Session.ID.String())
+}
+
+func (t *TokenServiceTest) TestRemoveCredentialsInternalErr() {
+	want := errors.New("Internal service error")
+
+	jwtSrv := mock.JwtServiceMock{}
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("RemoveCache", t.Session.ID.String()).Return(errors.New("Cannot connect to redis"))
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	err := srv.RemoveCredentials(t.Session.ID.String())
+
+	assert.Equal(t.T(), want.Error(), err.Error())
+}
+
+func (t *TokenServiceTest) TestCreateServiceTokenSuccess() {
+	want := &auth_proto.IssueServiceTokenResponse{
+		AccessToken: "service-token",
+		TokenType:   auth.BEARER,
+		ExpiresIn:   defaultServiceExpiresIn,
+		Scope:       "user:read user:checkin",
+	}
+
+	scopes := []string{"user:read", "user:checkin"}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+	jwtSrv.On("SignService", "checkin", scopes, int32(defaultServiceExpiresIn)).Return("service-token", nil)
+
+	srv := NewService(&jwtSrv, &cache.RepositoryMock{})
+
+	actual, err := srv.CreateServiceToken("checkin", scopes)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) serviceTokenDecoded() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       t.Conf.Issuer,
+		"sub":       "checkin",
+		"iat":       float64(time.Now().Unix()),
+		"exp":       float64(time.Now().Add(5 * time.Minute).Unix()),
+		"client_id": "checkin",
+		"scope":     "user:read user:checkin",
+	}
+}
+
+func (t *TokenServiceTest) TestValidateServiceTokenSuccess() {
+	want := &dto.UserCredential{
+		ClientId: "checkin",
+		Scopes:   []string{"user:read", "user:checkin"},
+	}
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.serviceTokenDecoded(),
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.Validate(token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+	cacheRepo.AssertNotCalled(t.T(), "GetCache")
+}
+
+func (t *TokenServiceTest) TestValidateServiceTokenEmptyClientId() {
+	token := faker.Word()
+
+	claims := t.serviceTokenDecoded()
+	claims["client_id"] = ""
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: claims,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	srv := NewService(&jwtSrv, &cache.RepositoryMock{})
+
+	actual, err := srv.Validate(token)
+
+	assert.Nil(t.T(), actual)
+	assert.NotNil(t.T(), err)
+}
+
+func (t *TokenServiceTest) TestIntrospectServiceToken() {
+	token := faker.Word()
+	claims := t.serviceTokenDecoded()
+
+	want := &dto.TokenIntrospection{
+		Active:    true,
+		ExpiresAt: int64(claims["exp"].(float64)),
+		IssuedAt:  int64(claims["iat"].(float64)),
+		Issuer:    t.Conf.Issuer,
+		Subject:   "checkin",
+		TokenType: auth.BEARER,
+		ClientId:  "checkin",
+		Scope:     "user:read user:checkin",
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: claims,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	srv := NewService(&jwtSrv, &cache.RepositoryMock{})
+
+	actual, err := srv.Introspect(token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *TokenServiceTest) TestCreateImpersonationCredentialsSuccess() {
+	actorId := faker.UUIDDigit()
+
+	cacheData := &dto.CacheAuth{
+		Token: t.Credential.AccessToken,
+		Role:  auth.USER,
+		Actor: actorId,
+	}
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+	jwtSrv.On("SignImpersonation", t.Auth, testify.AnythingOfType("string"), actorId, int32(defaultImpersonationExpiresIn)).Return(t.Credential.AccessToken, nil)
+
+	cacheRepo := cache.RepositoryMock{
+		V: map[string]interface{}{},
+	}
+	cacheRepo.On("SaveCache", testify.AnythingOfType("string"), cacheData, defaultImpersonationExpiresIn).Return(nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.CreateImpersonationCredentials(t.Auth, actorId)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), t.Credential.AccessToken, actual.AccessToken)
+	assert.Equal(t.T(), int32(defaultImpersonationExpiresIn), actual.ExpiresIn)
+	assert.Empty(t.T(), actual.RefreshToken)
+
+	sessionId := jwtSrv.Calls[len(jwtSrv.Calls)-1].Arguments.String(1)
+	assert.Contains(t.T(), cacheRepo.V, sessionId)
+	assert.NotEqual(t.T(), t.Session.ID.String(), sessionId)
+}
+
+func (t *TokenServiceTest) TestValidateImpersonationToken() {
+	actorId := faker.UUIDDigit()
+
+	want := &dto.UserCredential{
+		UserId:    t.Token.Claims.(dto.TokenPayloadAuth).UserId,
+		SessionId: t.Token.Claims.(dto.TokenPayloadAuth).SessionId,
+		Role:      auth.Role(t.Auth.Role),
+		Actor:     actorId,
+	}
+	token := faker.Word()
+
+	jwtSrv := mock.JwtServiceMock{}
+	jwtSrv.On("VerifyAuth", token).Return(&jwt.Token{
+		Claims: t.TokenDecoded,
+		Valid:  true,
+	}, nil)
+	jwtSrv.On("GetConfig").Return(t.Conf, nil)
+
+	cacheRepo := cache.RepositoryMock{}
+	cacheRepo.On("GetCache", t.TokenDecoded["session_id"], &dto.CacheAuth{}).Return(&dto.CacheAuth{Token: token, Role: auth.USER, Actor: actorId}, nil)
+
+	srv := NewService(&jwtSrv, &cacheRepo)
+
+	actual, err := srv.Validate(token)
+
+	assert.Nilf(t.T(), err, "error: %v", err)
+	assert.Equal(t.T(), want, actual)
+}
@@ -2,22 +2,89 @@ package strategy
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
 	"github.com/pkg/errors"
 )
 
 type JwtStrategy struct {
-	secret string
+	key              *SigningKey
+	verificationKeys []*SigningKey
+	keys             map[string]*SigningKey
 }
 
-func NewJwtStrategy(secret string) *JwtStrategy {
-	return &JwtStrategy{secret: secret}
+func NewJwtStrategy(conf cfgldr.Jwt) (*JwtStrategy, error) {
+	key, err := LoadSigningKey(cfgldr.JwtKey{
+		KeyID:      conf.KeyID,
+		Algorithm:  conf.Algorithm,
+		Secret:     conf.Secret,
+		PrivateKey: conf.PrivateKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*SigningKey{key.ID: key}
+	var verificationKeys []*SigningKey
+
+	for _, keyConf := range conf.VerificationKeys {
+		verificationKey, err := LoadSigningKey(keyConf)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error occurs while loading the verification key %v", keyConf.KeyID))
+		}
+
+		if _, ok := keys[verificationKey.ID]; ok {
+			return nil, errors.New(fmt.Sprintf("duplicate key id %v", verificationKey.ID))
+		}
+
+		keys[verificationKey.ID] = verificationKey
+		verificationKeys = append(verificationKeys, verificationKey)
+	}
+
+	return &JwtStrategy{key: key, verificationKeys: verificationKeys, keys: keys}, nil
 }
 
+// AuthDecode selects the verification key by the kid header, a token without kid can only match a key without id
 func (s *JwtStrategy) AuthDecode(token *jwt.Token) (interface{}, error) {
-	if _, isValid := token.Method.(*jwt.SigningMethodHMAC); !isValid {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := s.keys[kid]
+	if !ok || key.IsRetired(time.Now()) {
+		return nil, errors.New(fmt.Sprintf("unknown key id %v\n", token.Header["kid"]))
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
 		return nil, errors.New(fmt.Sprintf("invalid token %v\n", token.Header["alg"]))
 	}
 
-	return []byte(s.secret), nil
+	return key.VerifyKey, nil
+}
+
+func (s *JwtStrategy) GetSigningKey() *SigningKey {
+	return s.key
+}
+
+// GetJwks publishes the current key first followed by every verification key that is not retired
+func (s *JwtStrategy) GetJwks() *dto.Jwks {
+	jwks := &dto.Jwks{Keys: []*dto.Jwk{}}
+
+	if jwk := s.key.Jwk(); jwk != nil {
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	now := time.Now()
+	for _, key := range s.verificationKeys {
+		if key.IsRetired(now) {
+			continue
+		}
+
+		if jwk := key.Jwk(); jwk != nil {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+
+	return jwks
 }
@@ -0,0 +1,250 @@
+package strategy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/stretchr/testify/suite"
+)
+
+type JwtStrategyTest struct {
+	suite.Suite
+	RsaKeyPath string
+	EcKeyPath  string
+}
+
+func TestJwtStrategy(t *testing.T) {
+	suite.Run(t, new(JwtStrategyTest))
+}
+
+func (t *JwtStrategyTest) SetupTest() {
+	dir := t.T().TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	t.Require().Nil(err)
+
+	t.RsaKeyPath = filepath.Join(dir, "rsa.pem")
+	t.writePem(t.RsaKeyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	t.Require().Nil(err)
+
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	t.Require().Nil(err)
+
+	t.EcKeyPath = filepath.Join(dir, "ec.pem")
+	t.writePem(t.EcKeyPath, "EC PRIVATE KEY", ecDer)
+}
+
+func (t *JwtStrategyTest) writePem(path string, blockType string, der []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	t.Require().Nil(err)
+}
+
+func (t *JwtStrategyTest) TestHS256NotPublished() {
+	stg, err := NewJwtStrategy(cfgldr.Jwt{Secret: faker.Word()})
+
+	t.Nil(err)
+	t.Equal(jwt.SigningMethodHS256, stg.GetSigningKey().Method)
+	t.Empty(stg.GetJwks().Keys)
+
+	testSignAndVerify(t, stg)
+}
+
+func (t *JwtStrategyTest) TestRS256() {
+	stg, err := NewJwtStrategy(cfgldr.Jwt{Algorithm: "RS256", KeyID: "rsa-key", PrivateKey: t.RsaKeyPath})
+
+	t.Nil(err)
+	t.Equal("rsa-key", stg.GetSigningKey().ID)
+
+	jwks := stg.GetJwks()
+	t.Len(jwks.Keys, 1)
+	t.Equal("RSA", jwks.Keys[0].Kty)
+	t.Equal("RS256", jwks.Keys[0].Alg)
+	t.Equal("rsa-key", jwks.Keys[0].Kid)
+	t.Equal("AQAB", jwks.Keys[0].E)
+	t.NotEmpty(jwks.Keys[0].N)
+
+	testSignAndVerify(t, stg)
+}
+
+func (t *JwtStrategyTest) TestES256ThumbprintKeyID() {
+	stg, err := NewJwtStrategy(cfgldr.Jwt{Algorithm: "ES256", PrivateKey: t.EcKeyPath})
+
+	t.Nil(err)
+
+	thumbprint, err := stg.GetSigningKey().Thumbprint()
+	t.Nil(err)
+	t.Equal(thumbprint, stg.GetSigningKey().ID)
+
+	jwks := stg.GetJwks()
+	t.Len(jwks.Keys, 1)
+	t.Equal("EC", jwks.Keys[0].Kty)
+	t.Equal("P-256", jwks.Keys[0].Crv)
+	t.Len(jwks.Keys[0].X, 43)
+	t.Len(jwks.Keys[0].Y, 43)
+
+	testSignAndVerify(t, stg)
+}
+
+func (t *JwtStrategyTest) TestRejectAlgorithmConfusion() {
+	rsaStg, err := NewJwtStrategy(cfgldr.Jwt{Algorithm: "RS256", PrivateKey: t.RsaKeyPath})
+	t.Nil(err)
+
+	hmacStg, err := NewJwtStrategy(cfgldr.Jwt{Secret: faker.Word()})
+	t.Nil(err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "user"}).SignedString(hmacStg.GetSigningKey().SignKey)
+	t.Nil(err)
+
+	_, err = jwt.Parse(token, rsaStg.AuthDecode)
+	t.NotNil(err)
+}
+
+func (t *JwtStrategyTest) TestRejectUnknownKeyID() {
+	stg, err := NewJwtStrategy(cfgldr.Jwt{Algorithm: "RS256", KeyID: "rsa-key", PrivateKey: t.RsaKeyPath})
+	t.Nil(err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "user"})
+	token.Header["kid"] = "another-key"
+
+	signed, err := token.SignedString(stg.GetSigningKey().SignKey)
+	t.Nil(err)
+
+	_, err = jwt.Parse(signed, stg.AuthDecode)
+	t.NotNil(err)
+}
+
+func (t *JwtStrategyTest) TestVerifyRotatedKey() {
+	oldStg, err := NewJwtStrategy(cfgldr.Jwt{Algorithm: "RS256", KeyID: "old-key", PrivateKey: t.RsaKeyPath})
+	t.Nil(err)
+
+	publicDer, err := x509.MarshalPKIXPublicKey(oldStg.GetSigningKey().VerifyKey)
+	t.Require().Nil(err)
+
+	publicKeyPath := filepath.Join(t.T().TempDir(), "old.pub.pem")
+	t.writePem(publicKeyPath, "PUBLIC KEY", publicDer)
+
+	stg, err := NewJwtStrategy(cfgldr.Jwt{
+		Algorithm:  "ES256",
+		KeyID:      "new-key",
+		PrivateKey: t.EcKeyPath,
+		VerificationKeys: []cfgldr.JwtKey{
+			{KeyID: "old-key", Algorithm: "RS256", PublicKey: publicKeyPath},
+		},
+	})
+	t.Nil(err)
+	t.Equal("new-key", stg.GetSigningKey().ID)
+
+	jwks := stg.GetJwks()
+	t.Len(jwks.Keys, 2)
+	t.Equal("new-key", jwks.Keys[0].Kid)
+	t.Equal("old-key", jwks.Keys[1].Kid)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "user"})
+	token.Header["kid"] = "old-key"
+
+	signed, err := token.SignedString(oldStg.GetSigningKey().SignKey)
+	t.Nil(err)
+
+	parsed, err := jwt.Parse(signed, stg.AuthDecode)
+	t.Nil(err)
+	t.True(parsed.Valid)
+
+	testSignAndVerify(t, stg)
+}
+
+func (t *JwtStrategyTest) TestVerifyLegacyKeyWithoutKeyID() {
+	secret := faker.Word()
+
+	stg, err := NewJwtStrategy(cfgldr.Jwt{
+		Algorithm:        "RS256",
+		PrivateKey:       t.RsaKeyPath,
+		VerificationKeys: []cfgldr.JwtKey{{Secret: secret}},
+	})
+	t.Nil(err)
+	t.Len(stg.GetJwks().Keys, 1)
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "user"}).SignedString([]byte(secret))
+	t.Nil(err)
+
+	_, err = jwt.Parse(signed, stg.AuthDecode)
+	t.Nil(err)
+}
+
+func (t *JwtStrategyTest) TestRejectRetiredKey() {
+	secret := faker.Word()
+
+	stg, err := NewJwtStrategy(cfgldr.Jwt{
+		Algorithm:  "RS256",
+		PrivateKey: t.RsaKeyPath,
+		VerificationKeys: []cfgldr.JwtKey{
+			{KeyID: "old-key", Secret: secret, RetireAt: time.Now().Add(-time.Minute).Format(time.RFC3339)},
+		},
+	})
+	t.Nil(err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "user"})
+	token.Header["kid"] = "old-key"
+
+	signed, err := token.SignedString([]byte(secret))
+	t.Nil(err)
+
+	_, err = jwt.Parse(signed, stg.AuthDecode)
+	t.NotNil(err)
+}
+
+func (t *JwtStrategyTest) TestInvalidConfig() {
+	_, err := NewJwtStrategy(cfgldr.Jwt{})
+	t.NotNil(err)
+
+	_, err = NewJwtStrategy(cfgldr.Jwt{Algorithm: "RS256", PrivateKey: filepath.Join(t.T().TempDir(), "missing.pem")})
+	t.NotNil(err)
+
+	_, err = NewJwtStrategy(cfgldr.Jwt{Algorithm: "ES256", PrivateKey: t.RsaKeyPath})
+	t.NotNil(err)
+
+	_, err = NewJwtStrategy(cfgldr.Jwt{Algorithm: "none", Secret: faker.Word()})
+	t.NotNil(err)
+
+	_, err = NewJwtStrategy(cfgldr.Jwt{
+		Algorithm:        "RS256",
+		KeyID:            "rsa-key",
+		PrivateKey:       t.RsaKeyPath,
+		VerificationKeys: []cfgldr.JwtKey{{KeyID: "rsa-key", Secret: faker.Word()}},
+	})
+	t.NotNil(err)
+
+	_, err = NewJwtStrategy(cfgldr.Jwt{
+		Secret:           faker.Word(),
+		VerificationKeys: []cfgldr.JwtKey{{KeyID: "old-key", Secret: faker.Word(), RetireAt: "tomorrow"}},
+	})
+	t.NotNil(err)
+}
+
+func testSignAndVerify(t *JwtStrategyTest, stg *JwtStrategy) {
+	key := stg.GetSigningKey()
+
+	token := jwt.NewWithClaims(key.Method, jwt.RegisteredClaims{Subject: "user"})
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	signed, err := token.SignedString(key.SignKey)
+	t.Nil(err)
+
+	parsed, err := jwt.Parse(signed, stg.AuthDecode)
+	t.Nil(err)
+	t.True(parsed.Valid)
+}
@@ -0,0 +1,217 @@
+package strategy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+	RetireAt  *time.Time
+}
+
+// LoadSigningKey loads a key from the config, a key that only has a public key can verify tokens but not sign them
+func LoadSigningKey(conf cfgldr.JwtKey) (*SigningKey, error) {
+	key, err := loadKey(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.RetireAt != "" {
+		retireAt, err := time.Parse(time.RFC3339, conf.RetireAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while parsing retire_at")
+		}
+
+		key.RetireAt = &retireAt
+	}
+
+	return key, nil
+}
+
+func loadKey(conf cfgldr.JwtKey) (*SigningKey, error) {
+	switch conf.Algorithm {
+	case "", jwt.SigningMethodHS256.Alg():
+		if conf.Secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+
+		return &SigningKey{
+			ID:        conf.KeyID,
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(conf.Secret),
+			VerifyKey: []byte(conf.Secret),
+		}, nil
+
+	case jwt.SigningMethodRS256.Alg():
+		if conf.PrivateKey == "" && conf.PublicKey != "" {
+			pem, err := os.ReadFile(conf.PublicKey)
+			if err != nil {
+				return nil, errors.Wrap(err, "error occurs while reading the public key")
+			}
+
+			key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, errors.Wrap(err, "error occurs while parsing the rsa public key")
+			}
+
+			return newAsymmetricSigningKey(conf.KeyID, jwt.SigningMethodRS256, nil, key)
+		}
+
+		pem, err := os.ReadFile(conf.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while reading the private key")
+		}
+
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while parsing the rsa private key")
+		}
+
+		return newAsymmetricSigningKey(conf.KeyID, jwt.SigningMethodRS256, key, &key.PublicKey)
+
+	case jwt.SigningMethodES256.Alg():
+		if conf.PrivateKey == "" && conf.PublicKey != "" {
+			pem, err := os.ReadFile(conf.PublicKey)
+			if err != nil {
+				return nil, errors.Wrap(err, "error occurs while reading the public key")
+			}
+
+			key, err := jwt.ParseECPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, errors.Wrap(err, "error occurs while parsing the ecdsa public key")
+			}
+
+			if key.Curve != elliptic.P256() {
+				return nil, errors.New("ES256 requires a P-256 public key")
+			}
+
+			return newAsymmetricSigningKey(conf.KeyID, jwt.SigningMethodES256, nil, key)
+		}
+
+		pem, err := os.ReadFile(conf.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while reading the private key")
+		}
+
+		key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, errors.Wrap(err, "error occurs while parsing the ecdsa private key")
+		}
+
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 private key")
+		}
+
+		return newAsymmetricSigningKey(conf.KeyID, jwt.SigningMethodES256, key, &key.PublicKey)
+
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported signing algorithm %v", conf.Algorithm))
+	}
+}
+
+func newAsymmetricSigningKey(id string, method jwt.SigningMethod, signKey interface{}, verifyKey interface{}) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        id,
+		Method:    method,
+		SignKey:   signKey,
+		VerifyKey: verifyKey,
+	}
+
+	if key.ID == "" {
+		thumbprint, err := key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+
+		key.ID = thumbprint
+	}
+
+	return key, nil
+}
+
+// IsRetired reports whether tokens signed by the key must no longer be accepted
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return k.RetireAt != nil && !now.Before(*k.RetireAt)
+}
+
+// Jwk returns the public part of the key, a symmetric key must never be published so it returns nil
+func (k *SigningKey) Jwk() *dto.Jwk {
+	switch key := k.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		return &dto.Jwk{
+			Kty: "RSA",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+
+		return &dto.Jwk{
+			Kty: "EC",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: key.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}
+
+	default:
+		return nil
+	}
+}
+
+// Thumbprint computes the RFC 7638 thumbprint of the public key
+func (k *SigningKey) Thumbprint() (string, error) {
+	jwk := k.Jwk()
+	if jwk == nil {
+		return "", errors.New("thumbprint is only available for asymmetric keys")
+	}
+
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	buddhistEraOffset        = 543
+	defaultAcademicYearStart = "06-01"
+)
+
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+// AcademicYear resolves the current Buddhist-era academic year, the year rolls over on the configured start date in Bangkok time
+type AcademicYear struct {
+	startMonth time.Month
+	startDay   int
+	override   int
+	now        func() time.Time
+}
+
+// NewAcademicYear parses the start date in the MM-DD format, a non-zero override (e.g. 2566) pins the academic year regardless of the clock
+func NewAcademicYear(startDate string, override int, now func() time.Time) (*AcademicYear, error) {
+	if startDate == "" {
+		startDate = defaultAcademicYearStart
+	}
+
+	start, err := time.Parse("01-02", startDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid academic year start date")
+	}
+
+	if override != 0 && override < 2500 {
+		return nil, errors.New(fmt.Sprintf("Invalid academic year %v, expected a full buddhist era year", override))
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+
+	return &AcademicYear{
+		startMonth: start.Month(),
+		startDay:   start.Day(),
+		override:   override,
+		now:        now,
+	}, nil
+}
+
+// Current returns the full Buddhist-era academic year, e.g. 2566
+func (a *AcademicYear) Current() int {
+	if a.override != 0 {
+		return a.override
+	}
+
+	now := a.now().In(bangkok)
+	year := now.Year()
+
+	if now.Before(time.Date(year, a.startMonth, a.startDay, 0, 0, 0, 0, bangkok)) {
+		year--
+	}
+
+	return year + buddhistEraOffset
+}
+
+// CalYearFromID calculates the study year of the student id for the current academic year
+func (a *AcademicYear) CalYearFromID(sid string) (string, error) {
+	return CalYearFromID(sid, a.Current()%100)
+}
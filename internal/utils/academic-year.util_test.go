@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AcademicYearUtilTest struct {
+	suite.Suite
+}
+
+func TestAcademicYearUtil(t *testing.T) {
+	suite.Run(t, new(AcademicYearUtilTest))
+}
+
+func fakeClock(t time.Time) func() time.Time {
+	return func() time.Time {
+		return t
+	}
+}
+
+func (t *AcademicYearUtilTest) TestCurrentFromClock() {
+	testCurrent(t.T(), time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), 2566)
+	testCurrent(t.T(), time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 2566)
+	testCurrent(t.T(), time.Date(2024, 6, 1, 0, 0, 0, 0, bangkok), 2567)
+}
+
+func (t *AcademicYearUtilTest) TestCurrentRollsOverInBangkokTime() {
+	// 2024-05-31T17:00Z is already 2024-06-01 in Bangkok
+	testCurrent(t.T(), time.Date(2024, 5, 31, 16, 59, 0, 0, time.UTC), 2566)
+	testCurrent(t.T(), time.Date(2024, 5, 31, 17, 0, 0, 0, time.UTC), 2567)
+}
+
+func testCurrent(t *testing.T, now time.Time, expect int) {
+	academicYear, err := NewAcademicYear("06-01", 0, fakeClock(now))
+
+	assert.Nil(t, err)
+	assert.Equal(t, expect, academicYear.Current())
+}
+
+func (t *AcademicYearUtilTest) TestCurrentOverride() {
+	academicYear, err := NewAcademicYear("", 2565, fakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	t.Nil(err)
+	t.Equal(2565, academicYear.Current())
+}
+
+func (t *AcademicYearUtilTest) TestCalYearFromID() {
+	academicYear, err := NewAcademicYear("08-01", 0, fakeClock(time.Date(2024, 8, 1, 0, 0, 0, 0, bangkok)))
+	t.Nil(err)
+
+	actual, err := academicYear.CalYearFromID("6430000021")
+
+	t.Nil(err)
+	t.Equal("4", actual)
+}
+
+func (t *AcademicYearUtilTest) TestNewAcademicYearInvalidStartDate() {
+	_, err := NewAcademicYear("2023-06-01", 0, nil)
+
+	t.NotNil(err)
+}
+
+func (t *AcademicYearUtilTest) TestNewAcademicYearInvalidOverride() {
+	_, err := NewAcademicYear("", 66, nil)
+
+	t.NotNil(err)
+}
@@ -8,10 +8,9 @@ import (
 	"github.com/pkg/errors"
 )
 
-const CurrentYear = 66
-
 var (
-	pattern = regexp.MustCompile("(\\d{10})@student.chula.ac.th")
+	// anchored and escaped, a look-alike domain such as 6530000021@student.chula.ac.th.example.com must not resolve to the student
+	pattern = regexp.MustCompile(`^(\d{10})@student\.chula\.ac\.th$`)
 )
 
 func GetFacultyFromID(sid string) (*utils.Faculty, error) {
@@ -26,7 +25,7 @@ func GetFacultyFromID(sid string) (*utils.Faculty, error) {
 	return &result, nil
 }
 
-func CalYearFromID(sid string) (string, error) {
+func CalYearFromID(sid string, currentYear int) (string, error) {
 	if len(sid) != 10 {
 		return "", errors.New("Invalid student id")
 	}
@@ -36,7 +35,7 @@ func CalYearFromID(sid string) (string, error) {
 		return "", errors.New("Invalid student id")
 	}
 
-	studYear := CurrentYear - yearIn + 1
+	studYear := currentYear - yearIn + 1
 	if studYear <= 0 {
 		return "", errors.New("Invalid student ID")
 	}
@@ -45,11 +44,9 @@ func CalYearFromID(sid string) (string, error) {
 }
 
 func GetOuidFromGmail(email string) (string, error) {
-	found := pattern.FindAllStringSubmatch(email, -1)
-
-	if len(found) > 0 && len(found[0]) > 1 {
-		return found[0][1], nil
-	} else {
+	if !pattern.MatchString(email) {
 		return "", errors.New("Invalid student email")
 	}
+
+	return pattern.FindStringSubmatch(email)[1], nil
 }
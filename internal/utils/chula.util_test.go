@@ -63,7 +63,7 @@ func (t *ChulaUtilTest) TestGetStudyYearSuccess() {
 func testGetStudyYearSuccess(t *testing.T, sid string, expect string) {
 	want := expect
 
-	actual, err := CalYearFromID(sid)
+	actual, err := CalYearFromID(sid, 66)
 
 	assert.Nil(t, err)
 	assert.Equal(t, want, actual)
@@ -85,7 +85,7 @@ func (t *ChulaUtilTest) TestCalStudyYearInvalidYear() {
 func testCalStudyYearInvalidInput(t *testing.T, sid string) {
 	want := "Invalid student id"
 
-	actual, err := CalYearFromID(sid)
+	actual, err := CalYearFromID(sid, 66)
 
 	assert.Equal(t, actual, "")
 	assert.Equal(t, want, err.Error())
@@ -109,3 +109,17 @@ func (t *ChulaUtilTest) TestInvalidStudentGmail_InvalidOuid() {
 
 	t.Equal(expected, err.Error())
 }
+
+func (t *ChulaUtilTest) TestInvalidStudentGmail_LookAlikeDomain() {
+	for _, gmail := range []string{
+		"6512345678@student-chula-ac-th.com",
+		"6512345678@student.chula.ac.th.evil.com",
+		"6512345678@studentxchulaxacxth",
+		"x6512345678@student.chula.ac.th",
+		"6512345678@student.chula.ac.th\nfoo@example.com",
+	} {
+		_, err := GetOuidFromGmail(gmail)
+
+		t.NotNil(err, gmail)
+	}
+}
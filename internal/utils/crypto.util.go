@@ -63,3 +63,19 @@ func Hash(bv []byte) string {
 	h.Write(bv)
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
+
+// RandomString returns n random bytes encoded in unpadded base64url
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge derives the S256 PKCE code challenge of the verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
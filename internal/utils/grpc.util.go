@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ClientInfo resolves the caller of a request, the addresses forwarded in the metadata are only trusted when the grpc peer is one of the trusted proxies
+type ClientInfo struct {
+	trustedProxies []*net.IPNet
+}
+
+// NewClientInfo parses the trusted proxies as ip addresses or CIDR ranges, no trusted proxy means the grpc peer is always the caller
+func NewClientInfo(trustedProxies []string) (*ClientInfo, error) {
+	c := &ClientInfo{}
+
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New(fmt.Sprintf("Invalid trusted proxy %v", proxy))
+			}
+
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+
+			proxy = fmt.Sprintf("%v/%v", proxy, bits)
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid trusted proxy")
+		}
+
+		c.trustedProxies = append(c.trustedProxies, ipNet)
+	}
+
+	return c, nil
+}
+
+// Get returns the ip address and user agent of the caller, a nil ClientInfo trusts no proxy
+func (c *ClientInfo) Get(ctx context.Context) (ipAddress string, userAgent string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ipAddress = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ipAddress); err == nil {
+			ipAddress = host
+		}
+	}
+
+	if c.isTrusted(ipAddress) {
+		if forwarded := getFirstMetadata(md, "x-forwarded-for"); forwarded != "" {
+			ipAddress = c.forwardedFor(forwarded)
+		} else if realIP := getFirstMetadata(md, "x-real-ip"); realIP != "" {
+			ipAddress = strings.TrimSpace(realIP)
+		}
+	}
+
+	userAgent = getFirstMetadata(md, "grpcgateway-user-agent")
+	if userAgent == "" {
+		userAgent = getFirstMetadata(md, "user-agent")
+	}
+
+	return
+}
+
+// forwardedFor walks the chain from the nearest hop, the first address that is not a trusted proxy is the caller
+func (c *ClientInfo) forwardedFor(forwarded string) string {
+	hops := strings.Split(forwarded, ",")
+
+	for i := len(hops) - 1; i > 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if !c.isTrusted(hop) {
+			return hop
+		}
+	}
+
+	return strings.TrimSpace(hops[0])
+}
+
+func (c *ClientInfo) isTrusted(address string) bool {
+	if c == nil {
+		return false
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range c.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetBearerToken returns the token from the authorization metadata, it is empty when the caller does not send a bearer token
+func GetBearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	scheme, token, ok := strings.Cut(getFirstMetadata(md, "authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+type credentialKey struct{}
+
+func NewCredentialContext(ctx context.Context, credential *dto.UserCredential) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+// GetCredential returns the credential attached by the auth interceptor
+func GetCredential(ctx context.Context) (*dto.UserCredential, bool) {
+	credential, ok := ctx.Value(credentialKey{}).(*dto.UserCredential)
+
+	return credential, ok && credential != nil
+}
+
+func getFirstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
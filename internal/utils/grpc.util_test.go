@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type GrpcUtilTest struct {
+	suite.Suite
+}
+
+func TestGrpcUtil(t *testing.T) {
+	suite.Run(t, new(GrpcUtilTest))
+}
+
+func peerContext(address string, pairs ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(address), Port: 54321},
+	})
+
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+}
+
+func (t *GrpcUtilTest) TestGetClientInfoFromPeer() {
+	ctx := peerContext("10.0.0.1", "user-agent", "grpc-go/1.56.1")
+
+	ipAddress, userAgent := (&ClientInfo{}).Get(ctx)
+
+	t.Equal("10.0.0.1", ipAddress)
+	t.Equal("grpc-go/1.56.1", userAgent)
+}
+
+func (t *GrpcUtilTest) TestGetClientInfoFromTrustedGateway() {
+	ctx := peerContext("10.0.0.1",
+		"x-forwarded-for", "198.51.100.1, 203.0.113.7, 10.0.0.2",
+		"user-agent", "grpc-go/1.56.1",
+		"grpcgateway-user-agent", "Mozilla/5.0",
+	)
+
+	clientInfo, err := NewClientInfo([]string{"10.0.0.0/8"})
+	t.Nil(err)
+
+	ipAddress, userAgent := clientInfo.Get(ctx)
+
+	t.Equal("203.0.113.7", ipAddress)
+	t.Equal("Mozilla/5.0", userAgent)
+}
+
+func (t *GrpcUtilTest) TestGetClientInfoRealIPFromTrustedGateway() {
+	ctx := peerContext("127.0.0.1", "x-real-ip", "203.0.113.7")
+
+	clientInfo, err := NewClientInfo([]string{"127.0.0.1"})
+	t.Nil(err)
+
+	ipAddress, _ := clientInfo.Get(ctx)
+
+	t.Equal("203.0.113.7", ipAddress)
+}
+
+func (t *GrpcUtilTest) TestGetClientInfoIgnoresUntrustedForwarding() {
+	ctx := peerContext("198.51.100.9", "x-forwarded-for", "203.0.113.7", "x-real-ip", "203.0.113.8")
+
+	clientInfo, err := NewClientInfo([]string{"10.0.0.0/8"})
+	t.Nil(err)
+
+	ipAddress, _ := clientInfo.Get(ctx)
+	t.Equal("198.51.100.9", ipAddress)
+
+	var noProxy *ClientInfo
+	ipAddress, _ = noProxy.Get(ctx)
+	t.Equal("198.51.100.9", ipAddress)
+}
+
+func (t *GrpcUtilTest) TestGetClientInfoEmptyContext() {
+	ipAddress, userAgent := (&ClientInfo{}).Get(context.Background())
+
+	t.Equal("", ipAddress)
+	t.Equal("", userAgent)
+}
+
+func (t *GrpcUtilTest) TestNewClientInfoInvalidProxy() {
+	_, err := NewClientInfo([]string{"gateway"})
+	t.NotNil(err)
+
+	_, err = NewClientInfo([]string{"10.0.0.0/33"})
+	t.NotNil(err)
+
+	_, err = NewClientInfo([]string{"::1", "fd00::/8"})
+	t.Nil(err)
+}
+
+func (t *GrpcUtilTest) TestGetBearerToken() {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer abc.def.ghi"))
+
+	t.Equal("abc.def.ghi", GetBearerToken(ctx))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic dXNlcjpwYXNz"))
+
+	t.Equal("", GetBearerToken(ctx))
+	t.Equal("", GetBearerToken(context.Background()))
+}
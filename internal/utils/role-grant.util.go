@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+var studentIDPattern = regexp.MustCompile(`^\d{10}$`)
+
+func IsStudentID(in string) bool {
+	return studentIDPattern.MatchString(in)
+}
+
+func IsValidRole(in string) bool {
+	for _, r := range auth.ROLES {
+		if string(r) == in {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseRoleGrants reads the student_id,role rows with an optional header, the last row wins when a student id is repeated
+func ParseRoleGrants(r io.Reader) ([]*dto.RoleGrant, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var result []*dto.RoleGrant
+	index := map[string]int{}
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid csv")
+		}
+
+		studentID := strings.TrimSpace(record[0])
+		role := strings.ToLower(strings.TrimSpace(record[1]))
+
+		if line == 1 && studentID == "student_id" {
+			continue
+		}
+
+		if !IsStudentID(studentID) {
+			return nil, errors.New(fmt.Sprintf("Invalid student id at line %v", line))
+		}
+
+		if !IsValidRole(role) {
+			return nil, errors.New(fmt.Sprintf("Invalid role at line %v", line))
+		}
+
+		if i, ok := index[studentID]; ok {
+			result[i].Role = role
+			continue
+		}
+
+		index[studentID] = len(result)
+		result = append(result, &dto.RoleGrant{
+			StudentID: studentID,
+			Role:      role,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RoleGrantUtilTest struct {
+	suite.Suite
+}
+
+func TestRoleGrantUtil(t *testing.T) {
+	suite.Run(t, new(RoleGrantUtilTest))
+}
+
+func (t *RoleGrantUtilTest) TestParseRoleGrantsSuccess() {
+	want := []*dto.RoleGrant{
+		{StudentID: "6431234521", Role: "baan_staff"},
+		{StudentID: "6531234523", Role: "admin"},
+	}
+
+	actual, err := ParseRoleGrants(strings.NewReader("student_id,role\n6431234521,event_staff\n6531234523, Admin\n6431234521,baan_staff\n"))
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *RoleGrantUtilTest) TestParseRoleGrantsWithoutHeader() {
+	want := []*dto.RoleGrant{
+		{StudentID: "6431234521", Role: "user"},
+	}
+
+	actual, err := ParseRoleGrants(strings.NewReader("6431234521,user"))
+
+	assert.Nil(t.T(), err)
+	assert.Equal(t.T(), want, actual)
+}
+
+func (t *RoleGrantUtilTest) TestParseRoleGrantsInvalid() {
+	testParseRoleGrantsInvalid(t.T(), "6431234521,superuser", "Invalid role at line 1")
+	testParseRoleGrantsInvalid(t.T(), "student_id,role\n64312345,admin", "Invalid student id at line 2")
+	testParseRoleGrantsInvalid(t.T(), "6431234521", "")
+}
+
+func testParseRoleGrantsInvalid(t *testing.T, in string, errMsg string) {
+	actual, err := ParseRoleGrants(strings.NewReader(in))
+
+	assert.Nil(t, actual)
+	assert.NotNil(t, err)
+
+	if errMsg != "" {
+		assert.Equal(t, errMsg, err.Error())
+	}
+}
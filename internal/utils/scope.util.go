@@ -0,0 +1,26 @@
+package utils
+
+import "strings"
+
+// HasScope reports whether the scope is granted, scopes are compared exactly
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseScopes splits a space separated scope string and drops the duplicates
+func ParseScopes(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !HasScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return scopes
+}
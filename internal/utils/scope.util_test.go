@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScopeUtilTest struct {
+	suite.Suite
+}
+
+func TestScopeUtil(t *testing.T) {
+	suite.Run(t, new(ScopeUtilTest))
+}
+
+func (t *ScopeUtilTest) TestParseScopes() {
+	t.Equal([]string{"user:read", "user:checkin"}, ParseScopes("  user:read user:checkin\tuser:read "))
+	t.Nil(ParseScopes(""))
+}
+
+func (t *ScopeUtilTest) TestHasScope() {
+	t.True(HasScope([]string{"user:read", "user:checkin"}, "user:checkin"))
+	t.False(HasScope([]string{"user:read"}, "user:read:all"))
+	t.False(HasScope(nil, "user:read"))
+}
@@ -0,0 +1,29 @@
+package audit
+
+import (
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	"github.com/stretchr/testify/mock"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) Create(in *entity.AuditEvent) error {
+	args := r.Called(in)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Find(filter *dto.AuditFilter, result *[]*entity.AuditEvent, total *int64) error {
+	args := r.Called(filter, result, total)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*entity.AuditEvent)
+	}
+
+	*total = args.Get(1).(int64)
+
+	return args.Error(2)
+}
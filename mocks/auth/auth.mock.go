@@ -1,9 +1,14 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/client"
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
 	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	audit_entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
 	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	auth_proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
 	user_proto "github.com/isd-sgcu/rpkm66-go-proto/rpkm66/backend/user/v1"
@@ -14,11 +19,11 @@ type RepositoryMock struct {
 	mock.Mock
 }
 
-func (r *RepositoryMock) FindByRefreshToken(id string, result *entity.Auth) error {
-	args := r.Called(id, result)
+func (r *RepositoryMock) FindByID(id string, in *entity.Auth) error {
+	args := r.Called(id, in)
 
 	if args.Get(0) != nil {
-		*result = *args.Get(0).(*entity.Auth)
+		*in = *args.Get(0).(*entity.Auth)
 	}
 
 	return args.Error(1)
@@ -54,6 +59,176 @@ func (r *RepositoryMock) Update(id string, in *entity.Auth) error {
 	return args.Error(1)
 }
 
+func (r *RepositoryMock) FindSessionByID(id string, result *entity.Session) error {
+	args := r.Called(id, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.Session)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindSessionsByUserID(uid string, result *[]*entity.Session) error {
+	args := r.Called(uid, result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*entity.Session)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateSession(in *entity.Session) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.Session)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateSession(id string, in *entity.Session) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.Session)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) DeleteSession(id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindRefreshToken(token string, result *entity.RefreshToken) error {
+	args := r.Called(token, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.RefreshToken)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateRefreshToken(in *entity.RefreshToken) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.RefreshToken)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) RotateRefreshToken(id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) DeleteRefreshTokensBySessionID(sessionId string) error {
+	args := r.Called(sessionId)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindRoleGrantByStudentID(sid string, result *entity.RoleGrant) error {
+	args := r.Called(sid, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.RoleGrant)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpsertRoleGrants(grants *[]*entity.RoleGrant) error {
+	args := r.Called(grants)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindIdentity(provider string, subject string, result *entity.Identity) error {
+	args := r.Called(provider, subject, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.Identity)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindPendingIdentityByEmail(provider string, email string, result *entity.Identity) error {
+	args := r.Called(provider, email, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.Identity)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) FindIdentitiesByAuthID(authId string, result *[]*entity.Identity) error {
+	args := r.Called(authId, result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*entity.Identity)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateIdentity(in *entity.Identity) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.Identity)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateIdentity(id string, in *entity.Identity) error {
+	args := r.Called(id, in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.Identity)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) DeleteIdentity(id string) error {
+	args := r.Called(id)
+
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) FindServiceClient(clientId string, result *entity.ServiceClient) error {
+	args := r.Called(clientId, result)
+
+	if args.Get(0) != nil {
+		*result = *args.Get(0).(*entity.ServiceClient)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateServiceClient(in *entity.ServiceClient) error {
+	args := r.Called(in)
+
+	if args.Get(0) != nil {
+		*in = *args.Get(0).(*entity.ServiceClient)
+	}
+
+	return args.Error(1)
+}
+
 type ChulaSSOClientMock struct {
 	mock.Mock
 }
@@ -96,8 +271,20 @@ type JwtServiceMock struct {
 	mock.Mock
 }
 
-func (s *JwtServiceMock) SignAuth(in *entity.Auth) (token string, err error) {
-	args := s.Called(in)
+func (s *JwtServiceMock) SignAuth(in *entity.Auth, session *entity.Session) (token string, err error) {
+	args := s.Called(in, session)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *JwtServiceMock) SignImpersonation(in *entity.Auth, sessionId string, actorId string, expiresIn int32) (token string, err error) {
+	args := s.Called(in, sessionId, actorId, expiresIn)
+
+	return args.String(0), args.Error(1)
+}
+
+func (s *JwtServiceMock) SignService(clientId string, scopes []string, expiresIn int32) (token string, err error) {
+	args := s.Called(clientId, scopes, expiresIn)
 
 	return args.String(0), args.Error(1)
 }
@@ -112,6 +299,12 @@ func (s *JwtServiceMock) VerifyAuth(token string) (decode *jwt.Token, err error)
 	return decode, args.Error(1)
 }
 
+func (s *JwtServiceMock) GetJwks() *dto.Jwks {
+	args := s.Called()
+
+	return args.Get(0).(*dto.Jwks)
+}
+
 func (s *JwtServiceMock) GetConfig() *cfgldr.Jwt {
 	args := s.Called()
 
@@ -122,8 +315,8 @@ type TokenServiceMock struct {
 	mock.Mock
 }
 
-func (s *TokenServiceMock) CreateCredentials(in *entity.Auth, secret string) (credential *auth_proto.Credential, err error) {
-	args := s.Called(in, secret)
+func (s *TokenServiceMock) CreateCredentials(in *entity.Auth, session *entity.Session, secret string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(in, session, secret)
 
 	if args.Get(0) != nil {
 		credential = args.Get(0).(*auth_proto.Credential)
@@ -132,6 +325,26 @@ func (s *TokenServiceMock) CreateCredentials(in *entity.Auth, secret string) (cr
 	return credential, args.Error(1)
 }
 
+func (s *TokenServiceMock) CreateImpersonationCredentials(in *entity.Auth, actorId string) (credential *auth_proto.Credential, err error) {
+	args := s.Called(in, actorId)
+
+	if args.Get(0) != nil {
+		credential = args.Get(0).(*auth_proto.Credential)
+	}
+
+	return credential, args.Error(1)
+}
+
+func (s *TokenServiceMock) CreateServiceToken(clientId string, scopes []string) (res *auth_proto.IssueServiceTokenResponse, err error) {
+	args := s.Called(clientId, scopes)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*auth_proto.IssueServiceTokenResponse)
+	}
+
+	return res, args.Error(1)
+}
+
 func (s *TokenServiceMock) Validate(token string) (payload *dto.UserCredential, err error) {
 	args := s.Called(token)
 
@@ -142,8 +355,204 @@ func (s *TokenServiceMock) Validate(token string) (payload *dto.UserCredential,
 	return payload, args.Error(1)
 }
 
+func (s *TokenServiceMock) Introspect(token string) (introspection *dto.TokenIntrospection, err error) {
+	args := s.Called(token)
+
+	if args.Get(0) != nil {
+		introspection = args.Get(0).(*dto.TokenIntrospection)
+	}
+
+	return introspection, args.Error(1)
+}
+
+func (s *TokenServiceMock) UpdateRole(sessionId string, userRole role.Role) error {
+	args := s.Called(sessionId, userRole)
+
+	return args.Error(0)
+}
+
 func (s *TokenServiceMock) CreateRefreshToken() string {
 	args := s.Called()
 
 	return args.String(0)
 }
+
+func (s *TokenServiceMock) RemoveCredentials(sessionId string) error {
+	args := s.Called(sessionId)
+
+	return args.Error(0)
+}
+
+func (s *TokenServiceMock) GetJwks() *dto.Jwks {
+	args := s.Called()
+
+	return args.Get(0).(*dto.Jwks)
+}
+
+type PermissionServiceMock struct {
+	mock.Mock
+}
+
+func (s *PermissionServiceMock) SeedDefaults() error {
+	args := s.Called()
+
+	return args.Error(0)
+}
+
+func (s *PermissionServiceMock) Load() error {
+	args := s.Called()
+
+	return args.Error(0)
+}
+
+func (s *PermissionServiceMock) Start() {
+	s.Called()
+}
+
+func (s *PermissionServiceMock) Close() {
+	s.Called()
+}
+
+func (s *PermissionServiceMock) FindByRole(r role.Role) (permissions []string, err error) {
+	args := s.Called(r)
+
+	if args.Get(0) != nil {
+		permissions = args.Get(0).([]string)
+	}
+
+	return permissions, args.Error(1)
+}
+
+func (s *PermissionServiceMock) HasPermission(r role.Role, permission string) (bool, error) {
+	args := s.Called(r, permission)
+
+	return args.Bool(0), args.Error(1)
+}
+
+type AuditServiceMock struct {
+	mock.Mock
+}
+
+func (s *AuditServiceMock) Record(ctx context.Context, event *audit_entity.AuditEvent) {
+	s.Called(ctx, event)
+}
+
+func (s *AuditServiceMock) Find(filter *dto.AuditFilter) (events []*audit_entity.AuditEvent, total int64, err error) {
+	args := s.Called(filter)
+
+	if args.Get(0) != nil {
+		events = args.Get(0).([]*audit_entity.AuditEvent)
+	}
+
+	return events, args.Get(1).(int64), args.Error(2)
+}
+
+type EligibilityServiceMock struct {
+	mock.Mock
+}
+
+func (s *EligibilityServiceMock) Check(studentID string) error {
+	args := s.Called(studentID)
+
+	return args.Error(0)
+}
+
+type GoogleOauthClientMock struct {
+	mock.Mock
+}
+
+func (c *GoogleOauthClientMock) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	args := c.Called(state, nonce, codeChallenge)
+
+	return args.String(0)
+}
+
+func (c *GoogleOauthClientMock) GetUserEmail(code string, codeVerifier string, nonce string) (res *client.GoogleUserEmailResponse, err error) {
+	args := c.Called(code, codeVerifier, nonce)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*client.GoogleUserEmailResponse)
+	}
+
+	return res, args.Error(1)
+}
+
+type RedirectProviderMock struct {
+	mock.Mock
+}
+
+func (p *RedirectProviderMock) Name() role.Provider {
+	args := p.Called()
+
+	return args.Get(0).(role.Provider)
+}
+
+func (p *RedirectProviderMock) LoginUrl(state *dto.LoginState) string {
+	args := p.Called(state)
+
+	return args.String(0)
+}
+
+func (p *RedirectProviderMock) Resolve(proof string, state *dto.LoginState) (identity *dto.Identity, err error) {
+	args := p.Called(proof, state)
+
+	if args.Get(0) != nil {
+		identity = args.Get(0).(*dto.Identity)
+	}
+
+	return identity, args.Error(1)
+}
+
+type StateServiceMock struct {
+	mock.Mock
+}
+
+func (s *StateServiceMock) Create(provider role.Provider) (state *dto.LoginState, err error) {
+	args := s.Called(provider)
+
+	if args.Get(0) != nil {
+		state = args.Get(0).(*dto.LoginState)
+	}
+
+	return state, args.Error(1)
+}
+
+func (s *StateServiceMock) Consume(provider role.Provider, in string) (state *dto.LoginState, err error) {
+	args := s.Called(provider, in)
+
+	if args.Get(0) != nil {
+		state = args.Get(0).(*dto.LoginState)
+	}
+
+	return state, args.Error(1)
+}
+
+type MagicLinkServiceMock struct {
+	mock.Mock
+}
+
+func (m *MagicLinkServiceMock) Create(email string) (string, error) {
+	args := m.Called(email)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MagicLinkServiceMock) Consume(token string) (res *dto.MagicLink, err error) {
+	args := m.Called(token)
+
+	if args.Get(0) != nil {
+		res = args.Get(0).(*dto.MagicLink)
+	}
+
+	return res, args.Error(1)
+}
+
+type MailerMock struct {
+	mock.Mock
+}
+
+func (m *MailerMock) Send(mail *dto.Mail) error {
+	args := m.Called(mail)
+
+	return args.Error(0)
+}
@@ -1,7 +1,8 @@
 package cache
 
 import (
-	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"reflect"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -22,8 +23,38 @@ func (t *RepositoryMock) GetCache(key string, v interface{}) error {
 	args := t.Called(key, v)
 
 	if args.Get(0) != nil {
-		*v.(*dto.CacheAuth) = *args.Get(0).(*dto.CacheAuth)
+		reflect.ValueOf(v).Elem().Set(reflect.ValueOf(args.Get(0)).Elem())
 	}
 
 	return args.Error(1)
 }
+
+func (t *RepositoryMock) RemoveCache(key string) error {
+	args := t.Called(key)
+
+	delete(t.V, key)
+
+	return args.Error(0)
+}
+
+func (t *RepositoryMock) PopCache(key string, v interface{}) error {
+	args := t.Called(key, v)
+
+	if args.Get(0) != nil {
+		reflect.ValueOf(v).Elem().Set(reflect.ValueOf(args.Get(0)).Elem())
+	}
+
+	delete(t.V, key)
+
+	return args.Error(1)
+}
+
+func (t *RepositoryMock) SaveCacheIfAbsent(key string, v interface{}, ttl int) (bool, error) {
+	args := t.Called(key, v, ttl)
+
+	if args.Bool(0) {
+		t.V[key] = v
+	}
+
+	return args.Bool(0), args.Error(1)
+}
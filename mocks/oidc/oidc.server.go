@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const keyID = "oidc-key"
+
+// Server is a minimal OpenID Connect provider for tests, it exchanges Code with CodeVerifier for an id token carrying Nonce
+// (IdClaims overrides its claims) and returns Claims from the userinfo endpoint
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+	Code         string
+	CodeVerifier string
+	AccessToken  string
+	Nonce        string
+	Claims       map[string]interface{}
+	IdClaims     jwt.MapClaims
+	Key          *rsa.PrivateKey
+}
+
+func NewServer(claims map[string]interface{}) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Code:         "valid-code",
+		CodeVerifier: "code-verifier",
+		AccessToken:  "access-token",
+		Nonce:        "nonce",
+		Claims:       claims,
+		Key:          key,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userinfo)
+	mux.HandleFunc("/jwks", s.jwks)
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"userinfo_endpoint":      s.URL + "/userinfo",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	redirect, err := url.Parse(r.URL.Query().Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	query := redirect.Query()
+	query.Set("code", s.Code)
+	query.Set("state", r.URL.Query().Get("state"))
+	redirect.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != s.Code || r.PostForm.Get("code_verifier") != s.CodeVerifier {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := s.IdToken(s.IdClaims)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"access_token": s.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// IdToken signs the id token of the subject in Claims, the given claims override the defaults
+func (s *Server) IdToken(override jwt.MapClaims) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":   s.URL,
+		"sub":   s.Claims["sub"],
+		"aud":   s.ClientID,
+		"nonce": s.Nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+
+	for k, v := range override {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+
+	return token.SignedString(s.Key)
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.Key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") != s.AccessToken {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, s.Claims)
+}
+
+func writeJson(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
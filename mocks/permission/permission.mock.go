@@ -0,0 +1,34 @@
+package permission
+
+import (
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
+	"github.com/stretchr/testify/mock"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) FindAll(result *[]*entity.RolePermission) error {
+	args := r.Called(result)
+
+	if args.Get(0) != nil {
+		*result = args.Get(0).([]*entity.RolePermission)
+	}
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) Count(result *int64) error {
+	args := r.Called(result)
+
+	*result = args.Get(0).(int64)
+
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) CreateBatch(in *[]*entity.RolePermission) error {
+	args := r.Called(in)
+
+	return args.Error(0)
+}
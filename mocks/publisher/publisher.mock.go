@@ -0,0 +1,50 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/pkg/errors"
+)
+
+// InMemoryPublisher keeps the published events, the first Failures calls fail and every call waits for Block when it is set
+type InMemoryPublisher struct {
+	Failures int
+	Block    chan struct{}
+	mu       sync.Mutex
+	calls    int
+	events   []*dto.AuditMessage
+}
+
+func (p *InMemoryPublisher) Publish(_ context.Context, event *dto.AuditMessage) error {
+	if p.Block != nil {
+		<-p.Block
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.calls <= p.Failures {
+		return errors.New("sink is unavailable")
+	}
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *InMemoryPublisher) Events() []*dto.AuditMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*dto.AuditMessage{}, p.events...)
+}
+
+func (p *InMemoryPublisher) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.calls
+}
@@ -6,9 +6,10 @@ import (
 )
 
 type GoogleOauthClient interface {
-	GetUserEmail(code string) (*client.GoogleUserEmailResponse, error)
+	AuthCodeURL(state string, nonce string, codeChallenge string) string
+	GetUserEmail(code string, codeVerifier string, nonce string) (*client.GoogleUserEmailResponse, error)
 }
 
-func NewGoogleOauthClient(conf *oauth2.Config) GoogleOauthClient {
-	return client.NewGoogleOauthClient(conf)
+func NewGoogleOauthClient(conf *oauth2.Config, hostedDomain string) GoogleOauthClient {
+	return client.NewGoogleOauthClient(conf, hostedDomain)
 }
@@ -0,0 +1,22 @@
+package oidc
+
+import (
+	"net/http"
+
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/client"
+)
+
+type OidcClient interface {
+	AuthCodeURL(state string, nonce string, codeChallenge string) string
+	GetClaims(code string, codeVerifier string, nonce string) (map[string]interface{}, error)
+}
+
+func NewOidcClient(conf cfgldr.OidcProvider, httpClient *http.Client) (OidcClient, error) {
+	c, err := client.NewOidcClient(conf, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,14 @@
+package jwks
+
+import (
+	"net/http"
+
+	"github.com/isd-sgcu/rpkm66-auth/internal/handler/jwks"
+	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
+)
+
+const Path = "/.well-known/jwks.json"
+
+func NewHandler(tokenService token_svc.Service) http.Handler {
+	return jwks.NewHandler(tokenService)
+}
@@ -0,0 +1,12 @@
+package auth
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/interceptor/auth"
+	permission_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
+	"google.golang.org/grpc"
+)
+
+func NewUnaryInterceptor(tokenService token_svc.Service, permissionService permission_svc.Service) grpc.UnaryServerInterceptor {
+	return auth.NewInterceptor(tokenService, permissionService, auth.DefaultPolicies).Unary
+}
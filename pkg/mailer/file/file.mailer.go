@@ -0,0 +1,15 @@
+package file
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/mailer/file"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/mailer"
+)
+
+func NewMailer(path string, debug bool) (mailer.Mailer, error) {
+	m, err := file.NewMailer(path, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,8 @@
+package mailer
+
+import dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+
+// Mailer delivers a plain text mail, the driver is picked by the mail config
+type Mailer interface {
+	Send(mail *dto.Mail) error
+}
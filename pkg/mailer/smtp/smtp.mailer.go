@@ -0,0 +1,16 @@
+package smtp
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/internal/mailer/smtp"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/mailer"
+)
+
+func NewMailer(conf cfgldr.Mail) (mailer.Mailer, error) {
+	m, err := smtp.NewMailer(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,11 @@
+package chula_sso
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/chula_sso"
+	sso_client "github.com/isd-sgcu/rpkm66-auth/pkg/client/chula_sso"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+)
+
+func NewProvider(client sso_client.ChulaSSO) provider.IdentityProvider {
+	return chula_sso.NewProvider(client)
+}
@@ -0,0 +1,18 @@
+package email
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/email"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/mailer"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+	magic_link_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/magic_link"
+)
+
+func NewProvider(magicLinkService magic_link_svc.Service, mailer mailer.Mailer, conf cfgldr.MagicLink) (provider.MagicLinkProvider, error) {
+	p, err := email.NewProvider(magicLinkService, mailer, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,11 @@
+package google
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/google"
+	google_oauth "github.com/isd-sgcu/rpkm66-auth/pkg/client/google_oauth"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+)
+
+func NewProvider(client google_oauth.GoogleOauthClient) provider.RedirectProvider {
+	return google.NewProvider(client)
+}
@@ -0,0 +1,17 @@
+package oidc
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/internal/provider/oidc"
+	oidc_client "github.com/isd-sgcu/rpkm66-auth/pkg/client/oidc"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
+)
+
+func NewProvider(client oidc_client.OidcClient, conf cfgldr.OidcProvider) (provider.RedirectProvider, error) {
+	p, err := oidc.NewProvider(client, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+)
+
+// IdentityProvider turns a provider specific proof (e.g. SSO ticket, OAuth code) into a normalized identity,
+// state is the consumed login state of a redirect login and nil otherwise, the returned error is a grpc status error
+type IdentityProvider interface {
+	Name() auth.Provider
+	Resolve(proof string, state *dto.LoginState) (*dto.Identity, error)
+}
+
+// RedirectProvider is an identity provider where the login starts by redirecting the user to the provider
+type RedirectProvider interface {
+	IdentityProvider
+	LoginUrl(state *dto.LoginState) string
+}
+
+// MagicLinkProvider is an identity provider where the login starts by mailing a one time link to the user
+type MagicLinkProvider interface {
+	IdentityProvider
+	SendLink(email string) error
+}
@@ -0,0 +1,15 @@
+package file
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/publisher/file"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+	"github.com/pkg/errors"
+)
+
+func NewPublisher(path string) (publisher.EventPublisher, error) {
+	if path == "" {
+		return nil, errors.New("audit file path is required")
+	}
+
+	return file.NewPublisher(path), nil
+}
@@ -0,0 +1,18 @@
+package publisher
+
+import (
+	"context"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+)
+
+// EventPublisher delivers an audit event to an external sink, the sink is picked by the audit config
+type EventPublisher interface {
+	Publish(ctx context.Context, event *dto.AuditMessage) error
+}
+
+// AsyncPublisher hands the events to a background worker, Close delivers what is left in the queue
+type AsyncPublisher interface {
+	EventPublisher
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,15 @@
+package queue
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/publisher/queue"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+)
+
+var (
+	ErrQueueFull = queue.ErrQueueFull
+	ErrClosed    = queue.ErrClosed
+)
+
+func NewPublisher(publisher publisher.EventPublisher, size int, maxRetries int) publisher.AsyncPublisher {
+	return queue.NewPublisher(publisher, size, maxRetries)
+}
@@ -0,0 +1,11 @@
+package redis
+
+import (
+	"github.com/go-redis/redis/v8"
+	redis_publisher "github.com/isd-sgcu/rpkm66-auth/internal/publisher/redis"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+)
+
+func NewPublisher(client *redis.Client, stream string, maxLen int64) publisher.EventPublisher {
+	return redis_publisher.NewPublisher(client, stream, maxLen)
+}
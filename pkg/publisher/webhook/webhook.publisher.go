@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/internal/publisher/webhook"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+)
+
+func NewPublisher(url string, secret string) (publisher.EventPublisher, error) {
+	p, err := webhook.NewPublisher(url, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,17 @@
+package audit
+
+import (
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	audit_repo "github.com/isd-sgcu/rpkm66-auth/internal/repository/audit"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(in *entity.AuditEvent) error
+	Find(filter *dto.AuditFilter, result *[]*entity.AuditEvent, total *int64) error
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return audit_repo.NewRepository(db)
+}
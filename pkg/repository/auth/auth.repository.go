@@ -7,10 +7,29 @@ import (
 )
 
 type Repository interface {
+	FindByID(id string, result *entity.Auth) error
 	FindByUserID(uid string, result *entity.Auth) error
-	FindByRefreshToken(refreshToken string, result *entity.Auth) error
 	Create(auth *entity.Auth) error
 	Update(id string, auth *entity.Auth) error
+	FindSessionByID(id string, result *entity.Session) error
+	FindSessionsByUserID(uid string, result *[]*entity.Session) error
+	CreateSession(session *entity.Session) error
+	UpdateSession(id string, session *entity.Session) error
+	DeleteSession(id string) error
+	FindRefreshToken(token string, result *entity.RefreshToken) error
+	CreateRefreshToken(token *entity.RefreshToken) error
+	RotateRefreshToken(id string) error
+	DeleteRefreshTokensBySessionID(sessionId string) error
+	FindRoleGrantByStudentID(sid string, result *entity.RoleGrant) error
+	UpsertRoleGrants(grants *[]*entity.RoleGrant) error
+	FindIdentity(provider string, subject string, result *entity.Identity) error
+	FindPendingIdentityByEmail(provider string, email string, result *entity.Identity) error
+	FindIdentitiesByAuthID(authId string, result *[]*entity.Identity) error
+	CreateIdentity(identity *entity.Identity) error
+	UpdateIdentity(id string, identity *entity.Identity) error
+	DeleteIdentity(id string) error
+	FindServiceClient(clientId string, result *entity.ServiceClient) error
+	CreateServiceClient(client *entity.ServiceClient) error
 }
 
 func NewRepository(db *gorm.DB) Repository {
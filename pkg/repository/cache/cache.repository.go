@@ -8,6 +8,9 @@ import (
 type Repository interface {
 	SaveCache(key string, value interface{}, ttl int) error
 	GetCache(key string, value interface{}) error
+	RemoveCache(key string) error
+	PopCache(key string, value interface{}) error
+	SaveCacheIfAbsent(key string, value interface{}, ttl int) (bool, error)
 }
 
 func NewRepository(client *redis.Client) Repository {
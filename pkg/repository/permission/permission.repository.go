@@ -0,0 +1,17 @@
+package permission
+
+import (
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/permission"
+	permission_repo "github.com/isd-sgcu/rpkm66-auth/internal/repository/permission"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	FindAll(result *[]*entity.RolePermission) error
+	Count(result *int64) error
+	CreateBatch(in *[]*entity.RolePermission) error
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return permission_repo.NewRepository(db)
+}
@@ -0,0 +1,21 @@
+package audit
+
+import (
+	"context"
+
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/audit"
+	audit_svc "github.com/isd-sgcu/rpkm66-auth/internal/service/audit"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/publisher"
+	audit_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/audit"
+)
+
+type Service interface {
+	Record(ctx context.Context, event *entity.AuditEvent)
+	Find(filter *dto.AuditFilter) ([]*entity.AuditEvent, int64, error)
+}
+
+func NewService(repo audit_repo.Repository, publisher publisher.EventPublisher, clientInfo *utils.ClientInfo) Service {
+	return audit_svc.NewService(repo, publisher, clientInfo)
+}
@@ -2,24 +2,31 @@ package auth
 
 import (
 	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
-	"github.com/isd-sgcu/rpkm66-auth/client"
 	proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
 	"github.com/isd-sgcu/rpkm66-auth/internal/service/auth"
-	"github.com/isd-sgcu/rpkm66-auth/pkg/client/chula_sso"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+	"github.com/isd-sgcu/rpkm66-auth/pkg/provider"
 	auth_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/auth"
+	audit_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/audit"
+	eligibility_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/eligibility"
+	permission_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/permission"
+	state_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/state"
 	token_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/token"
 	user_svc "github.com/isd-sgcu/rpkm66-auth/pkg/service/user"
-	"golang.org/x/oauth2"
 )
 
 func NewService(
 	repo auth_repo.Repository,
-	chulaSSOClient chula_sso.ChulaSSO,
+	providers []provider.IdentityProvider,
 	tokenService token_svc.Service,
 	userService user_svc.Service,
+	permissionService permission_svc.Service,
+	eligibilityService eligibility_svc.Service,
+	stateService state_svc.Service,
+	auditService audit_svc.Service,
+	clientInfo *utils.ClientInfo,
+	academicYear *utils.AcademicYear,
 	conf cfgldr.App,
-	oauth *oauth2.Config,
-	googleOauthClient *client.GoogleOauthClient,
 ) proto.AuthServiceServer {
-	return auth.NewService(repo, chulaSSOClient, tokenService, userService, conf, oauth, googleOauthClient)
+	return auth.NewService(repo, providers, tokenService, userService, permissionService, eligibilityService, stateService, auditService, clientInfo, academicYear, conf)
 }
@@ -0,0 +1,20 @@
+package eligibility
+
+import (
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	"github.com/isd-sgcu/rpkm66-auth/internal/service/eligibility"
+	"github.com/isd-sgcu/rpkm66-auth/internal/utils"
+)
+
+type Service interface {
+	Check(studentID string) error
+}
+
+func NewService(conf cfgldr.Eligibility, academicYear *utils.AcademicYear) (Service, error) {
+	s, err := eligibility.NewService(conf, academicYear)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
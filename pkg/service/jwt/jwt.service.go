@@ -3,14 +3,18 @@ package jwt
 import (
 	_jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
 	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	"github.com/isd-sgcu/rpkm66-auth/internal/service/jwt"
 	"github.com/isd-sgcu/rpkm66-auth/pkg/strategy"
 )
 
 type Service interface {
-	SignAuth(in *entity.Auth) (string, error)
+	SignAuth(in *entity.Auth, session *entity.Session) (string, error)
+	SignImpersonation(in *entity.Auth, sessionId string, actorId string, expiresIn int32) (string, error)
+	SignService(clientId string, scopes []string, expiresIn int32) (string, error)
 	VerifyAuth(token string) (*_jwt.Token, error)
+	GetJwks() *dto.Jwks
 	GetConfig() *cfgldr.Jwt
 }
 
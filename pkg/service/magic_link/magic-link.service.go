@@ -0,0 +1,21 @@
+package magic_link
+
+import (
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/service/magic_link"
+	cache_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/cache"
+)
+
+type Service interface {
+	Create(email string) (string, error)
+	Consume(token string) (*dto.MagicLink, error)
+}
+
+func NewService(cacheRepository cache_repo.Repository, ttl int, cooldown int) Service {
+	return magic_link.NewService(cacheRepository, ttl, cooldown)
+}
+
+var (
+	ErrInvalidToken    = magic_link.ErrInvalidToken
+	ErrTooManyRequests = magic_link.ErrTooManyRequests
+)
@@ -0,0 +1,20 @@
+package permission
+
+import (
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	permission_svc "github.com/isd-sgcu/rpkm66-auth/internal/service/permission"
+	permission_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/permission"
+)
+
+type Service interface {
+	SeedDefaults() error
+	Load() error
+	Start()
+	Close()
+	FindByRole(r role.Role) ([]string, error)
+	HasPermission(r role.Role, permission string) (bool, error)
+}
+
+func NewService(repo permission_repo.Repository, refreshInterval int) Service {
+	return permission_svc.NewService(repo, refreshInterval)
+}
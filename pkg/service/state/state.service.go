@@ -0,0 +1,19 @@
+package state
+
+import (
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
+	"github.com/isd-sgcu/rpkm66-auth/internal/service/state"
+	cache_repo "github.com/isd-sgcu/rpkm66-auth/pkg/repository/cache"
+)
+
+type Service interface {
+	Create(provider role.Provider) (*dto.LoginState, error)
+	Consume(provider role.Provider, state string) (*dto.LoginState, error)
+}
+
+func NewService(cacheRepository cache_repo.Repository, ttl int) Service {
+	return state.NewService(cacheRepository, ttl)
+}
+
+var ErrInvalidState = state.ErrInvalidState
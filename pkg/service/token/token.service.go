@@ -1,6 +1,7 @@
 package token
 
 import (
+	role "github.com/isd-sgcu/rpkm66-auth/constant/auth"
 	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
 	entity "github.com/isd-sgcu/rpkm66-auth/internal/entity/auth"
 	proto "github.com/isd-sgcu/rpkm66-auth/internal/proto/rpkm66/auth/auth/v1"
@@ -10,9 +11,15 @@ import (
 )
 
 type Service interface {
-	CreateCredentials(auth *entity.Auth, secret string) (*proto.Credential, error)
+	CreateCredentials(auth *entity.Auth, session *entity.Session, secret string) (*proto.Credential, error)
+	CreateImpersonationCredentials(auth *entity.Auth, actorId string) (*proto.Credential, error)
+	CreateServiceToken(clientId string, scopes []string) (*proto.IssueServiceTokenResponse, error)
 	Validate(token string) (*dto.UserCredential, error)
+	Introspect(token string) (*dto.TokenIntrospection, error)
+	UpdateRole(sessionId string, userRole role.Role) error
 	CreateRefreshToken() string
+	RemoveCredentials(sessionId string) error
+	GetJwks() *dto.Jwks
 }
 
 func NewTokenService(jwtService jwt_svc.Service, cacheRepository cache_repo.Repository) Service {
@@ -2,13 +2,22 @@ package strategy
 
 import (
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/isd-sgcu/rpkm66-auth/cfgldr"
+	dto "github.com/isd-sgcu/rpkm66-auth/internal/dto/auth"
 	"github.com/isd-sgcu/rpkm66-auth/internal/strategy"
 )
 
 type JwtStrategy interface {
 	AuthDecode(token *jwt.Token) (interface{}, error)
+	GetSigningKey() *strategy.SigningKey
+	GetJwks() *dto.Jwks
 }
 
-func NewJwtStrategy(secret string) JwtStrategy {
-	return strategy.NewJwtStrategy(secret)
+func NewJwtStrategy(conf cfgldr.Jwt) (JwtStrategy, error) {
+	s, err := strategy.NewJwtStrategy(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
 }